@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Ways to group log dir sizes:
+const (
+	sizeByBroker = "broker"
+	sizeByLogDir = "log-dir"
+)
+
+func (cli *CLI) initAdminBrokers() {
+	cli.SetCommand("adminBrokers", "admin", cli.adminBrokersCommand())
+
+	logDirsCommand := cli.adminBrokersLogDirsCommand()
+	logDirsCommand.PersistentFlags().String("by", sizeByLogDir, "What to total sizes by [log-dir, broker]")
+	logDirsCommand.PersistentFlags().String("sort", sortBySize, "How to sort [size, name]")
+	cli.SetCommand("adminBrokersLogDirs", "adminBrokers", logDirsCommand)
+}
+
+// adminBrokersCommand deals with brokers:
+func (cli *CLI) adminBrokersCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "brokers",
+		Short: "Work with brokers",
+	}
+}
+
+// adminBrokersLogDirsCommand deals with reporting how much disk brokers use:
+func (cli *CLI) adminBrokersLogDirsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "log-dirs",
+		Short: "Show how much disk every log dir (or broker) uses, and which log dirs are offline",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the by flag:
+			by, err := cmd.Flags().GetString("by")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "by").Fatal("Unable to get flag")
+			}
+			if by != sizeByLogDir && by != sizeByBroker {
+				cli.logger.WithField("by", by).Fatal("Sizes can be totalled by log-dir or broker")
+			}
+
+			// Get the sort flag:
+			sortBy, err := cmd.Flags().GetString("sort")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "sort").Fatal("Unable to get flag")
+			}
+			if sortBy != sortBySize && sortBy != sortByName {
+				cli.logger.WithField("sort", sortBy).Fatal("Sizes can be sorted by size or name")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Describing log dirs")
+
+			// Ask every broker about its log dirs:
+			logDirs, replicas, err := cli.describeLogDirs()
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to describe log dirs")
+			}
+
+			// Total them up (starting every log dir at zero, so empty ones are shown too):
+			key := func(broker int, path string) sizeKey {
+				if by == sizeByBroker {
+					return sizeKey{Broker: broker}
+				}
+				return sizeKey{Broker: broker, LogDir: path}
+			}
+			totals := totalSizes(replicas, func(replica replicaSize) sizeKey { return key(replica.Broker, replica.LogDir) })
+			dirErrors := make(map[sizeKey][]error)
+			for _, dir := range logDirs {
+				if totals[key(dir.Broker, dir.Path)] == nil {
+					totals[key(dir.Broker, dir.Path)] = &sizeTotal{}
+				}
+				if dir.Error != nil {
+					dirErrors[key(dir.Broker, dir.Path)] = append(dirErrors[key(dir.Broker, dir.Path)], dir.Error)
+				}
+			}
+
+			message := "Log dir usage"
+			if by == sizeByBroker {
+				message = "Broker usage"
+			}
+			var offline int
+			var totalBytes int64
+			for _, usageKey := range sortedSizes(totals, sortBy) {
+				logger := cli.logger.
+					WithField("broker", usageKey.Broker).
+					WithField("bytes", totals[usageKey].Bytes).
+					WithField("replicas", totals[usageKey].Replicas).
+					WithField("size", humanBytes(totals[usageKey].Bytes))
+				if by == sizeByLogDir {
+					logger = logger.WithField("log_dir", usageKey.LogDir)
+				}
+				totalBytes += totals[usageKey].Bytes
+
+				// Offline log dirs (and brokers which couldn't be asked) don't report their replicas:
+				if len(dirErrors[usageKey]) > 0 {
+					offline += len(dirErrors[usageKey])
+					logger.WithField("errors", dirErrors[usageKey]).Warn(message + " (offline, or unable to describe)")
+					continue
+				}
+				logger.Info(message)
+			}
+
+			cli.logger.
+				WithField("bytes", totalBytes).
+				WithField("log_dirs", len(logDirs)).
+				WithField("offline", offline).
+				WithField("size", humanBytes(totalBytes)).
+				Info("Total usage")
+		},
+	}
+}
@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/segmentio/kafka-go"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
@@ -8,6 +12,15 @@ import (
 
 func (cli *CLI) initAdminConfig() {
 	cli.SetCommand("adminConfig", "admin", cli.adminConfigCommand())
+
+	healthCommand := cli.adminConfigHealthCommand()
+	healthCommand.PersistentFlags().IntSlice("expected-brokers", nil, "The broker IDs which should be in the cluster (brokers holding replicas are always expected)")
+	healthCommand.PersistentFlags().Int("max-leaderless", 0, "How many leaderless partitions to tolerate")
+	healthCommand.PersistentFlags().Int("max-missing-brokers", 0, "How many missing brokers to tolerate")
+	healthCommand.PersistentFlags().Int("max-offline", 0, "How many partitions with offline replicas to tolerate")
+	healthCommand.PersistentFlags().Int("max-under-min-isr", 0, "How many partitions with fewer in-sync replicas than min.insync.replicas to tolerate")
+	healthCommand.PersistentFlags().Int("max-under-replicated", 0, "How many under-replicated partitions to tolerate")
+	cli.SetCommand("adminConfigHealth", "adminConfig", healthCommand)
 	cli.SetCommand("adminConfigMetadata", "adminConfig", cli.adminConfigMetadataCommand())
 	cli.SetCommand("adminConfigParams", "adminConfig", cli.adminConfigParamsCommand())
 }
@@ -20,6 +33,119 @@ func (cli *CLI) adminConfigCommand() *cobra.Command {
 	}
 }
 
+// adminConfigHealthCommand deals with checking the health of the cluster:
+func (cli *CLI) adminConfigHealthCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Check the cluster for missing brokers, leaderless, offline, under-replicated and under-min-ISR partitions (exiting non-zero when there are too many)",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the expected-brokers flag:
+			expectedBrokers, err := cmd.Flags().GetIntSlice("expected-brokers")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "expected-brokers").Fatal("Unable to get flag")
+			}
+
+			// Get the threshold flags:
+			var thresholds healthThresholds
+			for flag, threshold := range map[string]*int{
+				"max-leaderless":       &thresholds.Leaderless,
+				"max-missing-brokers":  &thresholds.MissingBrokers,
+				"max-offline":          &thresholds.OfflineReplicas,
+				"max-under-min-isr":    &thresholds.UnderMinISR,
+				"max-under-replicated": &thresholds.UnderReplicated,
+			} {
+				if *threshold, err = cmd.Flags().GetInt(flag); err != nil {
+					cli.logger.WithError(err).WithField("flag", flag).Fatal("Unable to get flag")
+				}
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Checking cluster health")
+
+			// Retrieve the state of every partition:
+			state, err := cli.clusterState()
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+			}
+
+			// Retrieve min.insync.replicas for every topic:
+			var topicNames []string
+			for _, partition := range state.Partitions {
+				if !slices.Contains(topicNames, partition.Topic) {
+					topicNames = append(topicNames, partition.Topic)
+				}
+			}
+			minISRValues, minISRErrors, err := cli.topicConfigValues(topicNames, "min.insync.replicas")
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve min.insync.replicas")
+			}
+			for topicName, err := range minISRErrors {
+				cli.logger.WithError(err).WithField("topic", topicName).Warn("Unable to retrieve min.insync.replicas (not checking the topic's ISR against it)")
+			}
+			minISR := make(map[string]int, len(minISRValues))
+			for topicName, value := range minISRValues {
+				if minISR[topicName], err = strconv.Atoi(value); err != nil {
+					cli.logger.WithError(err).WithField("topic", topicName).Warn("Invalid min.insync.replicas")
+				}
+			}
+
+			// Report each problem:
+			report := checkHealth(state, minISR, expectedBrokers)
+			if report.ControllerMissing {
+				cli.logger.WithField("controller_id", state.Controller).Warn("No controller in the metadata")
+			}
+			for _, broker := range report.MissingBrokers {
+				cli.logger.WithField("broker", broker).Warn("Broker missing from metadata")
+			}
+			for _, problem := range []struct {
+				message    string
+				partitions []partitionState
+			}{
+				{"Partition has no leader", report.Leaderless},
+				{"Partition has offline replicas", report.OfflineReplicas},
+				{"Partition is under min ISR", report.UnderMinISR},
+				{"Partition is under-replicated", report.UnderReplicated},
+			} {
+				for _, partition := range problem.partitions {
+					cli.logger.
+						WithField("isr", partition.Isr).
+						WithField("leader", partition.Leader).
+						WithField("min_isr", minISR[partition.Topic]).
+						WithField("offline", partition.Offline).
+						WithField("partition", partition.Partition).
+						WithField("replicas", partition.Replicas).
+						WithField("topic", partition.Topic).
+						Warn(problem.message)
+				}
+			}
+
+			cli.logger.
+				WithField("brokers", len(state.Brokers)).
+				WithField("controller_id", state.Controller).
+				WithField("leaderless", len(report.Leaderless)).
+				WithField("missing_brokers", len(report.MissingBrokers)).
+				WithField("offline", len(report.OfflineReplicas)).
+				WithField("partitions", len(state.Partitions)).
+				WithField("topics", len(topicNames)).
+				WithField("under_min_isr", len(report.UnderMinISR)).
+				WithField("under_replicated", len(report.UnderReplicated)).
+				Info("Cluster health")
+
+			// Fail if anything is over its threshold:
+			if breaches := report.breaches(thresholds); len(breaches) > 0 {
+				cli.logger.WithField("breaches", strings.Join(breaches, ", ")).Fatal("Cluster is unhealthy")
+			}
+			cli.logger.Info("Cluster is healthy")
+		},
+	}
+}
+
 // adminConfigMetadataCommand deals with cluster metadata:
 func (cli *CLI) adminConfigMetadataCommand() *cobra.Command {
 	return &cobra.Command{
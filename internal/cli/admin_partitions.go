@@ -0,0 +1,949 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol/alterpartitionreassignments"
+	"github.com/segmentio/kafka-go/protocol/electleaders"
+	"github.com/spf13/cobra"
+)
+
+// Replication throttle configs (the same ones kafka-reassign-partitions.sh sets):
+const (
+	throttleFollowerRate     = "follower.replication.throttled.rate"
+	throttleFollowerReplicas = "follower.replication.throttled.replicas"
+	throttleLeaderRate       = "leader.replication.throttled.rate"
+	throttleLeaderReplicas   = "leader.replication.throttled.replicas"
+)
+
+// How long the controller gets to act on reassignment requests:
+const reassignmentTimeout = 30 * time.Second
+
+func (cli *CLI) initAdminPartitions() {
+	cli.SetCommand("adminPartitions", "admin", cli.adminPartitionsCommand())
+	cli.SetCommand("adminPartitionsReassign", "adminPartitions", cli.adminPartitionsReassignCommand())
+
+	generateCommand := cli.adminPartitionsReassignGenerateCommand()
+	generateCommand.PersistentFlags().Bool("all-topics", false, "Plan for every (non-internal) topic")
+	generateCommand.PersistentFlags().IntSlice("brokers", nil, "The brokers to spread replicas over (defaults to every broker in the cluster)")
+	generateCommand.PersistentFlags().String("current-file", "", "Also write the current assignment to this file (to roll back with)")
+	generateCommand.PersistentFlags().Int("replication-factor", 0, "Change the replication factor (0 keeps each topic's)")
+	generateCommand.PersistentFlags().StringSlice("topics", nil, "The topics to plan for")
+	generateCommand.PersistentFlags().String("topics-file", "", "Read the topics to plan for from a topics-to-move JSON file")
+	cli.SetCommand("adminPartitionsReassignGenerate", "adminPartitionsReassign", generateCommand)
+
+	executeCommand := cli.adminPartitionsReassignExecuteCommand()
+	executeCommand.PersistentFlags().Int64("throttle", 0, "Limit replication traffic for the moves to this many bytes/s on each broker (0 for no limit)")
+	cli.SetCommand("adminPartitionsReassignExecute", "adminPartitionsReassign", executeCommand)
+
+	statusCommand := cli.adminPartitionsReassignStatusCommand()
+	statusCommand.PersistentFlags().Bool("remove-throttle", false, "Remove the replication throttle once there's nothing left in progress")
+	cli.SetCommand("adminPartitionsReassignStatus", "adminPartitionsReassign", statusCommand)
+
+	cli.SetCommand("adminPartitionsReassignCancel", "adminPartitionsReassign", cli.adminPartitionsReassignCancelCommand())
+
+	electLeadersCommand := cli.adminPartitionsElectLeadersCommand()
+	electLeadersCommand.PersistentFlags().Bool("all", false, "Elect leaders for every partition which needs one")
+	electLeadersCommand.PersistentFlags().Int("partition", -1, "Only elect a leader for one partition of the topic (-1 for every partition)")
+	electLeadersCommand.PersistentFlags().String("topic", "", "Elect leaders for the partitions of a topic")
+	electLeadersCommand.PersistentFlags().String("type", "preferred", "The type of election [preferred, unclean] (unclean elections can lose messages)")
+	cli.SetCommand("adminPartitionsElectLeaders", "adminPartitions", electLeadersCommand)
+
+	cli.SetCommand("adminPartitionsLeaders", "adminPartitions", cli.adminPartitionsLeadersCommand())
+	cli.SetCommand("adminPartitionsAudit", "adminPartitions", cli.adminPartitionsAuditCommand())
+}
+
+// adminPartitionsCommand deals with managing partitions:
+func (cli *CLI) adminPartitionsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "partitions",
+		Short: "Work with partitions",
+	}
+}
+
+// adminPartitionsReassignCommand deals with moving partition replicas between brokers:
+func (cli *CLI) adminPartitionsReassignCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reassign",
+		Short: "Plan, execute, follow and cancel partition reassignments (using Kafka's reassignment JSON)",
+	}
+}
+
+// adminPartitionsReassignGenerateCommand deals with planning reassignments:
+func (cli *CLI) adminPartitionsReassignGenerateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate",
+		Short: "Print a balanced reassignment plan (eg after adding or decommissioning brokers, or to change the replication factor)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the topic flags:
+			allTopics, err := cmd.Flags().GetBool("all-topics")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "all-topics").Fatal("Unable to get flag")
+			}
+			topicNames, err := cmd.Flags().GetStringSlice("topics")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topics").Fatal("Unable to get flag")
+			}
+			topicsFile, err := cmd.Flags().GetString("topics-file")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topics-file").Fatal("Unable to get flag")
+			}
+			if topicsFile != "" {
+				fileTopicNames, err := loadTopicsToMoveFile(topicsFile)
+				if err != nil {
+					cli.logger.WithError(err).WithField("file", topicsFile).Fatal("Unable to read the topics file")
+				}
+				topicNames = append(topicNames, fileTopicNames...)
+			}
+			if allTopics == (len(topicNames) > 0) {
+				cli.logger.Fatal("Give either --all-topics, or some topics (with --topics or --topics-file)")
+			}
+
+			// Get the other flags:
+			brokers, err := cmd.Flags().GetIntSlice("brokers")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "brokers").Fatal("Unable to get flag")
+			}
+			currentFile, err := cmd.Flags().GetString("current-file")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "current-file").Fatal("Unable to get flag")
+			}
+			replicationFactor, err := cmd.Flags().GetInt("replication-factor")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "replication-factor").Fatal("Unable to get flag")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Planning a reassignment for topics: %v", topicNames)
+
+			// Get the current assignment:
+			state, err := cli.clusterState(topicNames...)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+			}
+			current := state.assignments(len(topicNames) > 0)
+			clusterBrokers, racks := state.Brokers, state.Racks
+			if len(brokers) == 0 {
+				brokers = clusterBrokers
+			}
+			for _, broker := range brokers {
+				if !slices.Contains(clusterBrokers, broker) {
+					cli.logger.WithField("broker", broker).Fatal("Broker not found in the cluster")
+				}
+			}
+
+			// Plan the new one:
+			proposed, err := planReassignment(current, brokers, racks, replicationFactor)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to plan a reassignment")
+			}
+			changed := changedAssignments(current, proposed)
+
+			// Save the current assignment (of the partitions which change) to roll back with:
+			if currentFile != "" {
+				var rollback []partitionAssignment
+				for _, assignment := range current {
+					for _, change := range changed {
+						if change.Topic == assignment.Topic && change.Partition == assignment.Partition {
+							rollback = append(rollback, assignment)
+						}
+					}
+				}
+				if err := saveReassignmentFile(currentFile, newReassignmentFile(rollback)); err != nil {
+					cli.logger.WithError(err).WithField("file", currentFile).Fatal("Unable to write the current assignment")
+				}
+			}
+
+			// Show the replicas (and preferred leaders) each broker would end up with:
+			beforeReplicas, beforeLeaders := brokerLoad(current)
+			afterReplicas, afterLeaders := brokerLoad(proposed)
+			for _, broker := range clusterBrokers {
+				cli.logger.
+					WithField("broker", broker).
+					WithField("leaders_after", afterLeaders[broker]).
+					WithField("leaders_before", beforeLeaders[broker]).
+					WithField("rack", racks[broker]).
+					WithField("replicas_after", afterReplicas[broker]).
+					WithField("replicas_before", beforeReplicas[broker]).
+					Info("Broker load")
+			}
+			cli.logger.
+				WithField("partitions", len(current)).
+				WithField("reassigned", len(changed)).
+				Info("Proposed partition reassignment")
+
+			// Print the plan:
+			output, err := newReassignmentFile(changed).JSON()
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to render the plan")
+			}
+			fmt.Println(output)
+		},
+	}
+}
+
+// adminPartitionsReassignExecuteCommand deals with starting reassignments:
+func (cli *CLI) adminPartitionsReassignExecuteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:        "execute <plan.json>",
+		Short:      "Start the reassignments in a plan (optionally throttling replication while they run)",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"plan"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the throttle flag:
+			throttle, err := cmd.Flags().GetInt64("throttle")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "throttle").Fatal("Unable to get flag")
+			}
+
+			// Read the plan:
+			plan, err := loadReassignmentFile(args[0])
+			if err != nil {
+				cli.logger.WithError(err).WithField("file", args[0]).Fatal("Unable to read the plan")
+			}
+			for _, assignment := range plan.Partitions {
+				for _, logDir := range assignment.LogDirs {
+					if logDir != "any" {
+						cli.logger.WithField("partition", assignment.Partition).WithField("topic", assignment.Topic).Warn("Log dirs are ignored (replicas go to any log dir)")
+						break
+					}
+				}
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Executing a reassignment of %d partitions", len(plan.Partitions))
+
+			// Throttle replication for the replicas which are moving:
+			if throttle > 0 {
+				state, err := cli.clusterState(planTopics(plan)...)
+				if err != nil {
+					cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+				}
+				current := state.assignments(true)
+				if err := cli.setThrottle(current, plan.Partitions, throttle); err != nil {
+					cli.logger.WithError(err).Fatal("Unable to set the replication throttle")
+				}
+			}
+
+			// Start the reassignments:
+			request := &kafka.AlterPartitionReassignmentsRequest{Timeout: reassignmentTimeout}
+			for _, assignment := range plan.Partitions {
+				request.Assignments = append(request.Assignments, kafka.AlterPartitionReassignmentsRequestAssignment{
+					BrokerIDs:   assignment.Replicas,
+					PartitionID: assignment.Partition,
+					Topic:       assignment.Topic,
+				})
+			}
+			response, err := cli.adminClient.AlterPartitionReassignments(context.TODO(), request)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to reassign partitions")
+			}
+			if response.Error != nil {
+				cli.logger.WithError(response.Error).Fatal("Unable to reassign partitions")
+			}
+
+			var failed int
+			for _, result := range response.PartitionResults {
+				if result.Error != nil {
+					failed++
+				}
+				cli.logger.
+					WithError(result.Error).
+					WithField("partition", result.PartitionID).
+					WithField("topic", result.Topic).
+					Info("Partition reassignment started")
+			}
+
+			cli.logger.
+				WithField("failed", failed).
+				WithField("partitions", len(plan.Partitions)).
+				WithField("throttle", throttle).
+				Info("Reassignment started (follow it with status)")
+		},
+	}
+}
+
+// adminPartitionsReassignStatusCommand deals with following reassignments:
+func (cli *CLI) adminPartitionsReassignStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:        "status [plan.json]",
+		Short:      "Show the reassignments in progress (and how far through a plan we are)",
+		Args:       cobra.MaximumNArgs(1),
+		ArgAliases: []string{"plan"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the remove-throttle flag:
+			removeThrottle, err := cmd.Flags().GetBool("remove-throttle")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "remove-throttle").Fatal("Unable to get flag")
+			}
+
+			// Read the plan (if we were given one):
+			plan := &reassignmentFile{}
+			if len(args) > 0 {
+				if plan, err = loadReassignmentFile(args[0]); err != nil {
+					cli.logger.WithError(err).WithField("file", args[0]).Fatal("Unable to read the plan")
+				}
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Retrieving reassignment status")
+
+			// List the reassignments in progress:
+			inProgress, err := cli.listReassignments()
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to list partition reassignments")
+			}
+			for _, topicName := range sortedKeys(inProgress) {
+				for _, partition := range inProgress[topicName] {
+					cli.logger.
+						WithField("adding", partition.AddingReplicas).
+						WithField("partition", partition.PartitionIndex).
+						WithField("removing", partition.RemovingReplicas).
+						WithField("replicas", partition.Replicas).
+						WithField("topic", topicName).
+						Info("Reassignment in progress")
+				}
+			}
+
+			// Compare the plan with where the replicas are now:
+			var complete, pending, running int
+			if len(plan.Partitions) > 0 {
+				state, err := cli.clusterState(planTopics(plan)...)
+				if err != nil {
+					cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+				}
+				current := state.assignments(true)
+				for _, assignment := range plan.Partitions {
+					if _, ok := inProgress[assignment.Topic][assignment.Partition]; ok {
+						running++
+						continue
+					}
+					if len(changedAssignments(current, []partitionAssignment{assignment})) == 0 {
+						complete++
+						continue
+					}
+					pending++
+					cli.logger.
+						WithField("partition", assignment.Partition).
+						WithField("replicas", assignment.Replicas).
+						WithField("topic", assignment.Topic).
+						Warn("Partition doesn't match the plan (and isn't being reassigned)")
+				}
+			} else {
+				for _, partitions := range inProgress {
+					running += len(partitions)
+				}
+			}
+
+			cli.logger.
+				WithField("complete", complete).
+				WithField("in_progress", running).
+				WithField("not_matching", pending).
+				Info("Reassignment status")
+
+			// Remove the throttle once everything has finished:
+			if removeThrottle {
+				if len(inProgress) > 0 {
+					cli.logger.Warn("Not removing the replication throttle while reassignments are in progress")
+					return
+				}
+				if err := cli.removeThrottle(planTopics(plan)); err != nil {
+					cli.logger.WithError(err).Fatal("Unable to remove the replication throttle")
+				}
+				cli.logger.Info("Replication throttle removed")
+			}
+		},
+	}
+}
+
+// adminPartitionsReassignCancelCommand deals with cancelling reassignments:
+func (cli *CLI) adminPartitionsReassignCancelCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:        "cancel [plan.json]",
+		Short:      "Cancel the reassignments in progress (every one, or just those in a plan)",
+		Args:       cobra.MaximumNArgs(1),
+		ArgAliases: []string{"plan"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Read the plan (if we were given one):
+			var plan *reassignmentFile
+			var err error
+			if len(args) > 0 {
+				if plan, err = loadReassignmentFile(args[0]); err != nil {
+					cli.logger.WithError(err).WithField("file", args[0]).Fatal("Unable to read the plan")
+				}
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Cancelling reassignments")
+
+			// Only the reassignments in progress can be cancelled:
+			inProgress, err := cli.listReassignments()
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to list partition reassignments")
+			}
+			requestTopics := make(map[string]*alterpartitionreassignments.RequestTopic)
+			for _, topicName := range sortedKeys(inProgress) {
+				for _, partition := range inProgress[topicName] {
+					if plan != nil && !slices.ContainsFunc(plan.Partitions, func(assignment partitionAssignment) bool {
+						return assignment.Topic == topicName && assignment.Partition == partition.PartitionIndex
+					}) {
+						continue
+					}
+					if requestTopics[topicName] == nil {
+						requestTopics[topicName] = &alterpartitionreassignments.RequestTopic{Name: topicName}
+					}
+					// No replicas (as opposed to an empty list) cancels a reassignment:
+					requestTopics[topicName].Partitions = append(requestTopics[topicName].Partitions, alterpartitionreassignments.RequestPartition{
+						PartitionIndex: int32(partition.PartitionIndex),
+					})
+				}
+			}
+			if len(requestTopics) == 0 {
+				cli.logger.Info("No reassignments to cancel")
+				return
+			}
+
+			// kafka-go can't send a cancellation, so we ask the controller directly:
+			request := &alterpartitionreassignments.Request{TimeoutMs: int32(reassignmentTimeout.Milliseconds())}
+			for _, topicName := range sortedKeys(requestTopics) {
+				request.Topics = append(request.Topics, *requestTopics[topicName])
+			}
+			response, err := cli.adminClient.Transport.RoundTrip(context.TODO(), cli.adminClient.Addr, request)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to cancel partition reassignments")
+			}
+			cancelResponse := response.(*alterpartitionreassignments.Response)
+			if cancelResponse.ErrorCode != 0 {
+				cli.logger.WithError(kafka.Error(cancelResponse.ErrorCode)).WithField("message", cancelResponse.ErrorMessage).Fatal("Unable to cancel partition reassignments")
+			}
+
+			var cancelled int
+			for _, result := range cancelResponse.Results {
+				for _, partition := range result.Partitions {
+					var partitionErr error
+					if partition.ErrorCode != 0 {
+						partitionErr = fmt.Errorf("%w: %s", kafka.Error(partition.ErrorCode), partition.ErrorMessage)
+					} else {
+						cancelled++
+					}
+					cli.logger.
+						WithError(partitionErr).
+						WithField("partition", partition.PartitionIndex).
+						WithField("topic", result.Name).
+						Info("Partition reassignment cancelled")
+				}
+			}
+
+			cli.logger.WithField("cancelled", cancelled).Info("Reassignments cancelled (the replication throttle is left in place, remove it with status --remove-throttle)")
+		},
+	}
+}
+
+// adminPartitionsElectLeadersCommand deals with leader elections:
+func (cli *CLI) adminPartitionsElectLeadersCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "elect-leaders",
+		Short: "Move leadership back to preferred replicas (or elect any replica as the leader of a leaderless partition with --type unclean)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the type flag:
+			electionTypeName, err := cmd.Flags().GetString("type")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "type").Fatal("Unable to get flag")
+			}
+			electionType, ok := electionTypes[electionTypeName]
+			if !ok {
+				cli.logger.WithField("type", electionTypeName).Fatal("Unknown election type")
+			}
+
+			// Get the partition flags:
+			all, err := cmd.Flags().GetBool("all")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "all").Fatal("Unable to get flag")
+			}
+			partitionID, err := cmd.Flags().GetInt("partition")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "partition").Fatal("Unable to get flag")
+			}
+			topicName, err := cmd.Flags().GetString("topic")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topic").Fatal("Unable to get flag")
+			}
+			if all == (topicName != "") {
+				cli.logger.Fatal("Give either --all, or a --topic (and optionally a --partition)")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Electing %s leaders", electionTypeName)
+
+			// Work out which partitions to hold elections for:
+			var topicNames []string
+			if topicName != "" {
+				topicNames = append(topicNames, topicName)
+			}
+			state, err := cli.clusterState(topicNames...)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+			}
+
+			requestTopics := make(map[string]*electleaders.RequestTopicPartitions)
+			var partitions int
+			for _, partition := range state.Partitions {
+				if partitionID >= 0 && partition.Partition != partitionID {
+					continue
+				}
+				// With --all we only bother the controller with the partitions which need an election:
+				if all && !partition.needsElection(electionType) {
+					continue
+				}
+				if requestTopics[partition.Topic] == nil {
+					requestTopics[partition.Topic] = &electleaders.RequestTopicPartitions{Topic: partition.Topic}
+				}
+				requestTopics[partition.Topic].PartitionIDs = append(requestTopics[partition.Topic].PartitionIDs, int32(partition.Partition))
+				partitions++
+			}
+			if partitions == 0 {
+				if partitionID >= 0 {
+					cli.logger.WithField("partition", partitionID).WithField("topic", topicName).Fatal("Partition not found")
+				}
+				cli.logger.WithField("type", electionTypeName).Info("No partitions need a leader election")
+				return
+			}
+			if electionType == electionUnclean {
+				cli.logger.WithField("partitions", partitions).Warn("Unclean leader elections can elect a replica which is missing messages (which are then lost)")
+			}
+
+			// kafka-go can only hold preferred elections, so we ask the controller directly:
+			request := &electleaders.Request{
+				ElectionType: electionType,
+				TimeoutMs:    int32(reassignmentTimeout.Milliseconds()),
+			}
+			for _, name := range sortedKeys(requestTopics) {
+				request.TopicPartitions = append(request.TopicPartitions, *requestTopics[name])
+			}
+			response, err := cli.adminClient.Transport.RoundTrip(context.TODO(), cli.adminClient.Addr, request)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to elect leaders")
+			}
+			electResponse := response.(*electleaders.Response)
+			if electResponse.ErrorCode != 0 {
+				cli.logger.WithError(kafka.Error(electResponse.ErrorCode)).Fatal("Unable to elect leaders")
+			}
+
+			var elected, failed, notNeeded int
+			for _, result := range electResponse.ReplicaElectionResults {
+				for _, partition := range result.PartitionResults {
+					logger := cli.logger.WithField("partition", partition.PartitionID).WithField("topic", result.Topic)
+					switch kafka.Error(partition.ErrorCode) {
+					case 0:
+						elected++
+						logger.Info("Leader elected")
+					case kafka.ElectionNotNeeded:
+						notNeeded++
+						logger.Debug("Leader election not needed")
+					default:
+						failed++
+						logger.WithError(kafka.Error(partition.ErrorCode)).WithField("message", partition.ErrorMessage).Warn("Unable to elect a leader")
+					}
+				}
+			}
+
+			cli.logger.
+				WithField("elected", elected).
+				WithField("failed", failed).
+				WithField("not_needed", notNeeded).
+				WithField("type", electionTypeName).
+				Info("Leader elections complete")
+		},
+	}
+}
+
+// adminPartitionsLeadersCommand deals with reporting leadership skew:
+func (cli *CLI) adminPartitionsLeadersCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "leaders [topic...]",
+		Short: "Show the partitions which aren't led by their preferred replica, and how leadership is spread over the brokers",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Checking partition leadership")
+
+			state, err := cli.clusterState(args...)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+			}
+
+			// Partitions which aren't led by their preferred replica (an election can only fix it if that replica is in sync):
+			var leaderless, notPreferred int
+			notPreferredByBroker := make(map[int]int)
+			for _, partition := range state.Partitions {
+				if !partition.needsElection(electionPreferred) {
+					continue
+				}
+				notPreferred++
+				notPreferredByBroker[partition.preferredLeader()]++
+				if partition.Leader < 0 {
+					leaderless++
+				}
+				cli.logger.
+					WithField("isr", partition.Isr).
+					WithField("leader", partition.Leader).
+					WithField("partition", partition.Partition).
+					WithField("preferred_in_sync", slices.Contains(partition.Isr, partition.preferredLeader())).
+					WithField("preferred_leader", partition.preferredLeader()).
+					WithField("topic", partition.Topic).
+					Info("Partition not led by its preferred replica")
+			}
+
+			// How many partitions each broker leads (and should lead). The imbalance is the share of its preferred partitions led elsewhere (as auto.leader.rebalance measures it):
+			leaders, preferred := state.leaderCounts()
+			for _, broker := range state.Brokers {
+				var imbalance float64
+				if preferred[broker] > 0 {
+					imbalance = float64(notPreferredByBroker[broker]) * 100 / float64(preferred[broker])
+				}
+				cli.logger.
+					WithField("broker", broker).
+					WithField("imbalance_percent", fmt.Sprintf("%.1f", imbalance)).
+					WithField("leaders", leaders[broker]).
+					WithField("preferred_leaders", preferred[broker]).
+					WithField("rack", state.Racks[broker]).
+					Info("Broker leadership")
+			}
+
+			cli.logger.
+				WithField("leaderless", leaderless).
+				WithField("not_preferred", notPreferred).
+				WithField("partitions", len(state.Partitions)).
+				Info("Partition leadership (elect-leaders --all moves leadership back to preferred replicas)")
+		},
+	}
+}
+
+// adminPartitionsAuditCommand deals with auditing replica placement:
+func (cli *CLI) adminPartitionsAuditCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "audit [topic...]",
+		Short: "Audit replica placement (rack diversity, leaders and replicas per broker and rack), printing a reassignment plan to fix it",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Auditing replica placement")
+
+			state, err := cli.clusterState(args...)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+			}
+			if len(distinctRacks(state.Brokers, state.Racks)) == 0 {
+				cli.logger.Warn("No brokers have a rack (set broker.rack to make replica placement rack-aware)")
+			}
+
+			// Internal topics are only audited when asked for by name:
+			var partitions []partitionState
+			for _, partition := range state.Partitions {
+				if !partition.Internal || len(args) > 0 {
+					partitions = append(partitions, partition)
+				}
+			}
+			audit := auditPlacement(state, partitions)
+
+			// Partitions with replicas sharing a rack (when there are racks to spare):
+			for _, partition := range audit.RackViolations {
+				racks := make([]string, len(partition.Replicas))
+				for i, replica := range partition.Replicas {
+					racks[i] = state.Racks[replica]
+				}
+				cli.logger.
+					WithField("partition", partition.Partition).
+					WithField("racks", racks).
+					WithField("replicas", partition.Replicas).
+					WithField("topic", partition.Topic).
+					Warn("Replicas share a rack")
+			}
+
+			// How replicas and leaders are spread:
+			for _, broker := range state.Brokers {
+				cli.logger.
+					WithField("broker", broker).
+					WithField("leaders", audit.BrokerLeaders[broker]).
+					WithField("rack", state.Racks[broker]).
+					WithField("replicas", audit.BrokerReplicas[broker]).
+					Info("Broker placement")
+			}
+			for _, rack := range sortedKeys(audit.RackReplicas) {
+				var brokers int
+				for _, broker := range state.Brokers {
+					if state.Racks[broker] == rack {
+						brokers++
+					}
+				}
+				cli.logger.
+					WithField("brokers", brokers).
+					WithField("leaders", audit.RackLeaders[rack]).
+					WithField("rack", rack).
+					WithField("replicas", audit.RackReplicas[rack]).
+					Info("Rack placement")
+			}
+
+			// Suggest a reassignment which fixes what it can (the same plan as reassign generate, keeping brokers which hold replicas but are missing from the metadata):
+			brokers, missing := replicaBrokers(state, partitions)
+			for _, broker := range missing {
+				cli.logger.WithField("broker", broker).Warn("Broker holds replicas but is missing from metadata (the suggested plan still counts it)")
+			}
+			current := state.assignments(len(args) > 0)
+			proposed, err := planReassignment(current, brokers, state.Racks, 0)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to plan a reassignment")
+			}
+			changed := changedAssignments(current, proposed)
+
+			cli.logger.
+				WithField("leader_skew", audit.LeaderSkew).
+				WithField("partitions", len(partitions)).
+				WithField("rack_violations", len(audit.RackViolations)).
+				WithField("replica_skew", audit.ReplicaSkew).
+				WithField("suggested_reassignments", len(changed)).
+				Info("Placement audit (leader skew can be fixed with elect-leaders, and the suggested plan with reassign execute)")
+
+			if len(changed) > 0 {
+				output, err := newReassignmentFile(changed).JSON()
+				if err != nil {
+					cli.logger.WithError(err).Fatal("Unable to render the plan")
+				}
+				fmt.Println(output)
+			}
+		},
+	}
+}
+
+// brokerLoad counts the replicas and preferred leaders on each broker:
+func brokerLoad(assignments []partitionAssignment) (map[int]int, map[int]int) {
+	replicas, leaders := make(map[int]int), make(map[int]int)
+	for _, assignment := range assignments {
+		if len(assignment.Replicas) > 0 {
+			leaders[assignment.Replicas[0]]++
+		}
+		for _, replica := range assignment.Replicas {
+			replicas[replica]++
+		}
+	}
+	return replicas, leaders
+}
+
+// planTopics returns the (sorted) topics in a plan:
+func planTopics(plan *reassignmentFile) []string {
+	var topicNames []string
+	for _, assignment := range plan.Partitions {
+		if !slices.Contains(topicNames, assignment.Topic) {
+			topicNames = append(topicNames, assignment.Topic)
+		}
+	}
+	sort.Strings(topicNames)
+	return topicNames
+}
+
+// sortedKeys returns the keys of a map in order:
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// throttledReplicas works out which replicas to throttle for each topic (as "partition:broker" lists):
+// leaders are the current replicas (which the moving ones copy from), followers are the replicas being added.
+func throttledReplicas(current, proposed []partitionAssignment) (map[string][]string, map[string][]string, []int) {
+	leaders, followers := make(map[string][]string), make(map[string][]string)
+	var brokers []int
+	addBroker := func(broker int) {
+		if !slices.Contains(brokers, broker) {
+			brokers = append(brokers, broker)
+		}
+	}
+
+	for _, assignment := range changedAssignments(current, proposed) {
+		var currentReplicas []int
+		for _, candidate := range current {
+			if candidate.Topic == assignment.Topic && candidate.Partition == assignment.Partition {
+				currentReplicas = candidate.Replicas
+			}
+		}
+
+		for _, replica := range currentReplicas {
+			leaders[assignment.Topic] = append(leaders[assignment.Topic], fmt.Sprintf("%d:%d", assignment.Partition, replica))
+			addBroker(replica)
+		}
+		for _, replica := range assignment.Replicas {
+			if !slices.Contains(currentReplicas, replica) {
+				followers[assignment.Topic] = append(followers[assignment.Topic], fmt.Sprintf("%d:%d", assignment.Partition, replica))
+			}
+			addBroker(replica)
+		}
+	}
+
+	sort.Ints(brokers)
+	return leaders, followers, brokers
+}
+
+// setThrottle limits replication traffic for the replicas which a reassignment moves:
+func (cli *CLI) setThrottle(current, proposed []partitionAssignment, rate int64) error {
+	leaders, followers, brokers := throttledReplicas(current, proposed)
+
+	// The rate is set on each broker (one at a time, as kafka-go can only alter one broker's configs per request):
+	for _, broker := range brokers {
+		err := cli.alterConfigs(kafka.ResourceTypeBroker, strconv.Itoa(broker), kafka.ConfigOperationSet, map[string]string{
+			throttleFollowerRate: strconv.FormatInt(rate, 10),
+			throttleLeaderRate:   strconv.FormatInt(rate, 10),
+		})
+		if err != nil {
+			return err
+		}
+		cli.logger.WithField("broker", broker).WithField("rate", rate).Debug("Throttled replication")
+	}
+
+	// The replicas to throttle are set on each topic:
+	for _, topicName := range sortedKeys(leaders) {
+		err := cli.alterConfigs(kafka.ResourceTypeTopic, topicName, kafka.ConfigOperationSet, map[string]string{
+			throttleFollowerReplicas: strings.Join(followers[topicName], ","),
+			throttleLeaderReplicas:   strings.Join(leaders[topicName], ","),
+		})
+		if err != nil {
+			return err
+		}
+		cli.logger.WithField("topic", topicName).Debug("Throttled replicas")
+	}
+
+	return nil
+}
+
+// removeThrottle removes the throttle rates from every broker (and the throttled replicas from some topics):
+func (cli *CLI) removeThrottle(topicNames []string) error {
+	state, err := cli.clusterState(topicNames...)
+	if err != nil {
+		return err
+	}
+
+	for _, broker := range state.Brokers {
+		err := cli.alterConfigs(kafka.ResourceTypeBroker, strconv.Itoa(broker), kafka.ConfigOperationDelete, map[string]string{
+			throttleFollowerRate: "",
+			throttleLeaderRate:   "",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, topicName := range topicNames {
+		err := cli.alterConfigs(kafka.ResourceTypeTopic, topicName, kafka.ConfigOperationDelete, map[string]string{
+			throttleFollowerReplicas: "",
+			throttleLeaderReplicas:   "",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alterConfigs sets (or deletes) some configs on a resource:
+func (cli *CLI) alterConfigs(resourceType kafka.ResourceType, resourceName string, operation kafka.ConfigOperation, configs map[string]string) error {
+	resource := kafka.IncrementalAlterConfigsRequestResource{
+		ResourceName: resourceName,
+		ResourceType: resourceType,
+	}
+	for _, name := range sortedKeys(configs) {
+		resource.Configs = append(resource.Configs, kafka.IncrementalAlterConfigsRequestConfig{
+			ConfigOperation: operation,
+			Name:            name,
+			Value:           configs[name],
+		})
+	}
+
+	response, err := cli.adminClient.IncrementalAlterConfigs(context.TODO(), &kafka.IncrementalAlterConfigsRequest{
+		Resources: []kafka.IncrementalAlterConfigsRequestResource{resource},
+	})
+	if err != nil {
+		return err
+	}
+	for _, result := range response.Resources {
+		if result.Error != nil {
+			return fmt.Errorf("unable to alter the configs of %s: %w", resourceName, result.Error)
+		}
+	}
+	return nil
+}
+
+// listReassignments returns the partition reassignments in progress (by topic and partition):
+func (cli *CLI) listReassignments() (map[string]map[int]kafka.ListPartitionReassignmentsResponsePartition, error) {
+	response, err := cli.adminClient.ListPartitionReassignments(context.TODO(), &kafka.ListPartitionReassignmentsRequest{
+		Timeout: reassignmentTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	inProgress := make(map[string]map[int]kafka.ListPartitionReassignmentsResponsePartition)
+	for topicName, topic := range response.Topics {
+		for _, partition := range topic.Partitions {
+			if inProgress[topicName] == nil {
+				inProgress[topicName] = make(map[int]kafka.ListPartitionReassignmentsResponsePartition)
+			}
+			inProgress[topicName][partition.PartitionIndex] = partition
+		}
+	}
+	return inProgress, nil
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"slices"
+
 	"github.com/segmentio/kafka-go"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
@@ -9,8 +11,19 @@ import (
 func (cli *CLI) initAdminTopics() {
 	cli.SetCommand("adminTopics", "admin", cli.adminTopicsCommand())
 	cli.SetCommand("adminTopicsDelete", "adminTopics", cli.adminTopicsDeleteCommand())
+
+	deleteRecordsCommand := cli.adminTopicsDeleteRecordsCommand()
+	deleteRecordsCommand.PersistentFlags().Bool("all", false, "Delete every record")
+	deleteRecordsCommand.PersistentFlags().Int64("before-offset", 0, "Delete every record before this offset")
+	deleteRecordsCommand.PersistentFlags().String("before-time", "", "Delete every record before this time (RFC3339, unix ms, or a duration ago such as 2h)")
+	deleteRecordsCommand.PersistentFlags().Int("partition", -1, "Only delete records from this partition")
+	cli.SetCommand("adminTopicsDeleteRecords", "adminTopics", deleteRecordsCommand)
 	cli.SetCommand("adminTopicsDescribe", "adminTopics", cli.adminTopicsDescribeCommand())
 	cli.SetCommand("adminTopicsList", "adminTopics", cli.adminTopicsListCommand())
+
+	offsetsCommand := cli.adminTopicsOffsetsCommand()
+	offsetsCommand.PersistentFlags().String("time", "", "Also look up the first offset at or after this time (RFC3339, unix ms, or a duration ago such as 2h)")
+	cli.SetCommand("adminTopicsOffsets", "adminTopics", offsetsCommand)
 }
 
 // adminTopicsCommand deals with managing topics:
@@ -54,6 +67,143 @@ func (cli *CLI) adminTopicsDeleteCommand() *cobra.Command {
 	}
 }
 
+// adminTopicsDeleteRecordsCommand deals with deleting records from the start of partitions:
+func (cli *CLI) adminTopicsDeleteRecordsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:        "delete-records <topic>",
+		Short:      "Delete the records before an offset or time (or all of them) from each partition, keeping the topic and its configs",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"topic"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the flags (exactly one of which says what to delete):
+			all, err := cmd.Flags().GetBool("all")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "all").Fatal("Unable to get flag")
+			}
+			beforeOffset, err := cmd.Flags().GetInt64("before-offset")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "before-offset").Fatal("Unable to get flag")
+			}
+			beforeTimeValue, err := cmd.Flags().GetString("before-time")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "before-time").Fatal("Unable to get flag")
+			}
+			beforeTime, err := timeFlag(beforeTimeValue)
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "before-time").Fatal("Invalid time")
+			}
+			partition, err := cmd.Flags().GetInt("partition")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "partition").Fatal("Unable to get flag")
+			}
+			given := 0
+			for _, changed := range []bool{all, cmd.Flags().Changed("before-offset"), !beforeTime.IsZero()} {
+				if changed {
+					given++
+				}
+			}
+			if given != 1 {
+				cli.logger.Fatal("Give exactly one of --before-offset, --before-time or --all")
+			}
+
+			// Get the topic name:
+			topicName := args[0]
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Deleting records from topic: %s", topicName)
+
+			// Get the partitions:
+			partitions, err := cli.topicPartitions(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to retrieve topic partitions")
+			}
+			if partition >= 0 {
+				if !slices.Contains(partitions, partition) {
+					cli.logger.WithField("partition", partition).WithField("topic", topicName).Fatal("Partition not found")
+				}
+				partitions = []int{partition}
+			}
+
+			// Look up the watermarks:
+			earliestOffsets, err := cli.listOffsets(topicName, partitions, kafka.FirstOffset)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the earliest offsets")
+			}
+			latestOffsets, err := cli.listOffsets(topicName, partitions, kafka.LastOffset)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the latest offsets")
+			}
+
+			// Work out where the records will start in each partition (which can't be beyond the high watermark):
+			beforeOffsets := make(map[int]int64, len(partitions))
+			switch {
+			case all:
+				for _, partition := range partitions {
+					beforeOffsets[partition] = latestOffsets[partition]
+				}
+			case !beforeTime.IsZero():
+				timeOffsets, err := cli.offsetsForTime(topicName, partitions, beforeTime)
+				if err != nil {
+					cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the offsets for a time")
+				}
+				for _, partition := range partitions {
+					// Nothing that recent means every record is older:
+					beforeOffsets[partition] = latestOffsets[partition]
+					if offset := timeOffsets[partition]; offset >= 0 && offset < latestOffsets[partition] {
+						beforeOffsets[partition] = offset
+					}
+				}
+			default:
+				for _, partition := range partitions {
+					beforeOffsets[partition] = min(beforeOffset, latestOffsets[partition])
+				}
+			}
+
+			// Only ask about partitions which have something to delete:
+			for partition, offset := range beforeOffsets {
+				if offset <= earliestOffsets[partition] {
+					delete(beforeOffsets, partition)
+					cli.logger.WithField("low_watermark", earliestOffsets[partition]).WithField("partition", partition).Infof("Nothing to delete [%s]", topicName)
+				}
+			}
+			if len(beforeOffsets) == 0 {
+				return
+			}
+
+			// Delete the records:
+			lowWatermarks, err := cli.deleteRecords(topicName, beforeOffsets)
+			var totalDeleted int64
+			for _, partition := range partitions {
+				lowWatermark, ok := lowWatermarks[partition]
+				if !ok {
+					continue
+				}
+				totalDeleted += lowWatermark - earliestOffsets[partition]
+				cli.logger.
+					WithField("deleted", lowWatermark-earliestOffsets[partition]).
+					WithField("high_watermark", latestOffsets[partition]).
+					WithField("low_watermark", lowWatermark).
+					WithField("partition", partition).
+					Infof("Records deleted [%s]", topicName)
+			}
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to delete records")
+			}
+
+			cli.logger.
+				WithField("deleted", totalDeleted).
+				WithField("partitions", len(lowWatermarks)).
+				Infof("Deleted records [%s]", topicName)
+		},
+	}
+}
+
 // adminTopicsDescribeCommand deals with describing topics:
 func (cli *CLI) adminTopicsDescribeCommand() *cobra.Command {
 
@@ -138,3 +288,84 @@ func (cli *CLI) adminTopicsListCommand() *cobra.Command {
 		},
 	}
 }
+
+// adminTopicsOffsetsCommand deals with looking up the offsets of each partition:
+func (cli *CLI) adminTopicsOffsetsCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:        "offsets <topic>",
+		Short:      "Show the earliest and latest offsets of each partition (and optionally the offset for a time)",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"topic"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the time flag:
+			timeValue, err := cmd.Flags().GetString("time")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "time").Fatal("Unable to get flag")
+			}
+			at, err := timeFlag(timeValue)
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "time").Fatal("Invalid time")
+			}
+
+			// Get the topic name:
+			topicName := args[0]
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Listing offsets for topic: %s", topicName)
+
+			// Get the partitions:
+			partitions, err := cli.topicPartitions(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to retrieve topic partitions")
+			}
+
+			// Look up the watermarks:
+			earliestOffsets, err := cli.listOffsets(topicName, partitions, kafka.FirstOffset)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the earliest offsets")
+			}
+			latestOffsets, err := cli.listOffsets(topicName, partitions, kafka.LastOffset)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the latest offsets")
+			}
+
+			// Look up the offsets for the time (if we were given one):
+			var timeOffsets map[int]int64
+			if !at.IsZero() {
+				if timeOffsets, err = cli.offsetsForTime(topicName, partitions, at); err != nil {
+					cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the offsets for a time")
+				}
+			}
+
+			// List the partitions:
+			var totalMessages int64
+			for _, partition := range partitions {
+				messages := latestOffsets[partition] - earliestOffsets[partition]
+				totalMessages += messages
+
+				entry := cli.logger.
+					WithField("earliest", earliestOffsets[partition]).
+					WithField("latest", latestOffsets[partition]).
+					WithField("messages", messages).
+					WithField("partition", partition)
+				if timeOffsets != nil {
+					entry = entry.WithField("offset_for_time", timeOffsets[partition])
+				}
+				entry.Infof("Partition offsets [%s]", topicName)
+			}
+
+			// The count is an estimate (compaction and transaction markers leave gaps):
+			cli.logger.
+				WithField("messages", totalMessages).
+				WithField("partitions", len(partitions)).
+				Infof("Estimated message count [%s]", topicName)
+		},
+	}
+}
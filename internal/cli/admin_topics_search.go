@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// searchCriteria are what a message has to match to be returned by a search:
+type searchCriteria struct {
+	headers    map[string]string
+	key        *string
+	valueRegex *regexp.Regexp
+}
+
+// match reports whether a record meets every criteria:
+func (sc *searchCriteria) match(r *record) bool {
+	if sc.key != nil && r.Key != *sc.key {
+		return false
+	}
+	for name, value := range sc.headers {
+		if headerValue, ok := r.Headers[name]; !ok || headerValue != value {
+			return false
+		}
+	}
+	if sc.valueRegex != nil && !sc.valueRegex.MatchString(r.Value) {
+		return false
+	}
+	return true
+}
+
+// searchStats count what a search has done so far (updated atomically by the workers):
+type searchStats struct {
+	errors  int64
+	matched int64
+	scanned int64
+}
+
+// searchAll searches some ranges with a bounded number of workers, sending each match to the channel it returns (which is closed once every range has been searched).
+// A range which fails is counted as an error, and the workers carry on with the rest:
+func searchAll(ranges []offsetRange, workers int, criteria *searchCriteria, stats *searchStats, search func(partitionRange offsetRange, callback func(r *record)) error) <-chan *record {
+	pending := make(chan offsetRange, len(ranges))
+	for _, partitionRange := range ranges {
+		pending <- partitionRange
+	}
+	close(pending)
+
+	matches := make(chan *record)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for partitionRange := range pending {
+				err := search(partitionRange, func(r *record) {
+					atomic.AddInt64(&stats.scanned, 1)
+					if criteria.match(r) {
+						atomic.AddInt64(&stats.matched, 1)
+						matches <- r
+					}
+				})
+				if err != nil {
+					atomic.AddInt64(&stats.errors, 1)
+				}
+			}
+		}()
+	}
+	go func() {
+		waitGroup.Wait()
+		close(matches)
+	}()
+
+	return matches
+}
+
+func (cli *CLI) initAdminTopicsSearch() {
+	searchCommand := cli.adminTopicsSearchCommand()
+	searchCommand.PersistentFlags().String("from", "", "Only search messages from this time (RFC3339, unix ms, or a duration ago such as 2h)")
+	searchCommand.PersistentFlags().String("to", "", "Only search messages up to this time (RFC3339, unix ms, or a duration ago such as 2h)")
+	searchCommand.PersistentFlags().Int("workers", 8, "How many partitions to search at once")
+	addSearchCriteriaFlags(searchCommand)
+	addDeserializerFlags(searchCommand)
+	addOutputFlags(searchCommand)
+	cli.SetCommand("adminTopicsSearch", "adminTopics", searchCommand)
+}
+
+// adminTopicsSearchCommand deals with searching topics for messages:
+func (cli *CLI) adminTopicsSearchCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:        "search <topic>",
+		Short:      "Search every partition of a topic for messages by key, header or value",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"topic"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the search criteria:
+			criteria, err := searchCriteriaFromFlags(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Invalid search criteria")
+			}
+
+			// Get the time range:
+			fromFlag, err := cmd.Flags().GetString("from")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "from").Fatal("Unable to get flag")
+			}
+			from, err := timeFlag(fromFlag)
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "from").Fatal("Invalid time")
+			}
+			toFlag, err := cmd.Flags().GetString("to")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "to").Fatal("Unable to get flag")
+			}
+			to, err := timeFlag(toFlag)
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "to").Fatal("Invalid time")
+			}
+
+			// Get the workers flag:
+			workers, err := cmd.Flags().GetInt("workers")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "workers").Fatal("Unable to get flag")
+			}
+			if workers < 1 {
+				workers = 1
+			}
+
+			// Get deserializers for the message keys, values and headers:
+			deserializers, err := cli.messageDeserializers(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare deserializers")
+			}
+
+			// Get a formatter for the output (which defaults to showing where each match is):
+			formatter, err := cli.recordFormatter(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare an output formatter")
+			}
+			if _, ok := formatter.(*valueFormatter); ok {
+				formatter = &fieldsFormatter{fields: []string{fieldPartition, fieldOffset, fieldTimestamp, fieldKey, fieldValue}}
+			}
+
+			// Get the topic name:
+			topicName := args[0]
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Searching topic: %s", topicName)
+
+			// Work out which offsets to search in each partition:
+			searchRanges, err := cli.offsetRanges(topicName, from, to)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to determine which offsets to search")
+			}
+
+			// Periodically report our progress:
+			startTime := time.Now()
+			stats := &searchStats{}
+			go func() {
+				for {
+					time.Sleep(time.Second)
+					cli.logger.WithField("errors", atomic.LoadInt64(&stats.errors)).WithField("matched", atomic.LoadInt64(&stats.matched)).WithField("scanned", atomic.LoadInt64(&stats.scanned)).WithField("messages/s", atomic.LoadInt64(&stats.scanned)/int64(time.Since(startTime).Seconds()+1)).Info("Progress report")
+				}
+			}()
+
+			// Search the partitions with a bounded number of workers:
+			matches := searchAll(searchRanges, workers, criteria, stats, func(partitionRange offsetRange, callback func(r *record)) error {
+				err := cli.searchPartition(topicName, partitionRange, deserializers, callback)
+				if err != nil {
+					cli.logger.WithError(err).WithField("partition", partitionRange.partition).Error("Unable to search partition")
+					return err
+				}
+				cli.logger.WithField("partition", partitionRange.partition).Debug("Finished searching partition")
+				return nil
+			})
+
+			// Print the matches as they arrive:
+			for match := range matches {
+				output, err := formatter.format(match)
+				if err != nil {
+					cli.logger.WithError(err).WithField("offset", match.Offset).WithField("partition", match.Partition).Error("Unable to format a message")
+					continue
+				}
+				fmt.Println(output)
+			}
+
+			cli.logger.
+				WithField("errors", stats.errors).
+				WithField("matched", stats.matched).
+				WithField("partitions", len(searchRanges)).
+				WithField("scanned", stats.scanned).
+				Info("Search complete")
+		},
+	}
+}
+
+// addSearchCriteriaFlags adds the flags which choose what a search matches:
+func addSearchCriteriaFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringArray("header", nil, "Match messages with this header, as name=value (repeatable)")
+	cmd.PersistentFlags().String("key", "", "Match messages with this (decoded) key")
+	cmd.PersistentFlags().String("value-regex", "", "Match messages whose (decoded) value matches this regular expression")
+}
+
+// searchCriteriaFromFlags gets the search criteria (at least one of which is required):
+func searchCriteriaFromFlags(cmd *cobra.Command) (*searchCriteria, error) {
+	criteria := &searchCriteria{headers: make(map[string]string)}
+
+	if cmd.Flags().Changed("key") {
+		key, err := cmd.Flags().GetString("key")
+		if err != nil {
+			return nil, err
+		}
+		criteria.key = &key
+	}
+
+	headers, err := cmd.Flags().GetStringArray("header")
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, "=")
+		if !found {
+			return nil, fmt.Errorf("headers must be given as name=value (not %s)", header)
+		}
+		criteria.headers[name] = value
+	}
+
+	valueRegex, err := cmd.Flags().GetString("value-regex")
+	if err != nil {
+		return nil, err
+	}
+	if valueRegex != "" {
+		if criteria.valueRegex, err = regexp.Compile(valueRegex); err != nil {
+			return nil, fmt.Errorf("invalid value regex: %w", err)
+		}
+	}
+
+	if criteria.key == nil && len(criteria.headers) == 0 && criteria.valueRegex == nil {
+		return nil, fmt.Errorf("at least one of --key, --header or --value-regex is required")
+	}
+
+	return criteria, nil
+}
+
+// searchPartition reads every message in a partition's range, handing each one (decoded) to a callback.
+// Reading gives up when nothing more arrives (the last offsets can be transaction markers, or compacted away), rather than waiting forever:
+func (cli *CLI) searchPartition(topicName string, partitionRange offsetRange, deserializers *messageDeserializers, callback func(r *record)) error {
+
+	reached, err := cli.readRange(topicName, partitionRange, readRangeIdleTimeout, func(message kafka.Message) error {
+
+		// Decode the message (searching undecodable messages by their raw key and value):
+		decoded, err := deserializers.record(message)
+		if decoded == nil {
+			cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Debug("Unable to deserialize a message")
+			decoded = deserializers.rawRecord(message)
+		}
+		decoded.TimestampType = cli.timestampType(message.Topic)
+		callback(decoded)
+		return nil
+	})
+	if err == nil && partitionRange.stoppedShort(reached) {
+		cli.logger.
+			WithField("end", partitionRange.end).
+			WithField("partition", partitionRange.partition).
+			WithField("reached", reached).
+			Warn("Search of partition stopped before the end of its range (the rest may only be transaction markers, or the broker is slow)")
+	}
+	return err
+}
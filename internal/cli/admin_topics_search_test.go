@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCriteriaMatch(t *testing.T) {
+	testRecord := &record{
+		Headers: map[string]string{"source": "web", "trace": "abc"},
+		Key:     "order-1",
+		Value:   `{"status":"failed"}`,
+	}
+	key, otherKey := "order-1", "order-2"
+
+	// Each criteria on its own:
+	assert.True(t, (&searchCriteria{key: &key}).match(testRecord))
+	assert.False(t, (&searchCriteria{key: &otherKey}).match(testRecord))
+	assert.True(t, (&searchCriteria{headers: map[string]string{"source": "web"}}).match(testRecord))
+	assert.False(t, (&searchCriteria{headers: map[string]string{"source": "mobile"}}).match(testRecord))
+	assert.False(t, (&searchCriteria{headers: map[string]string{"missing": ""}}).match(testRecord))
+	assert.True(t, (&searchCriteria{valueRegex: regexp.MustCompile(`"status":"fail`)}).match(testRecord))
+	assert.False(t, (&searchCriteria{valueRegex: regexp.MustCompile(`"status":"ok"`)}).match(testRecord))
+
+	// Every criteria has to match:
+	assert.True(t, (&searchCriteria{headers: map[string]string{"source": "web", "trace": "abc"}, key: &key, valueRegex: regexp.MustCompile(`failed`)}).match(testRecord))
+	assert.False(t, (&searchCriteria{headers: map[string]string{"source": "web", "trace": "xyz"}, key: &key}).match(testRecord))
+	assert.False(t, (&searchCriteria{key: &otherKey, valueRegex: regexp.MustCompile(`failed`)}).match(testRecord))
+}
+
+func TestSearchCriteriaFromFlags(t *testing.T) {
+	parse := func(args ...string) (*searchCriteria, error) {
+		cmd := &cobra.Command{}
+		addSearchCriteriaFlags(cmd)
+		assert.NoError(t, cmd.ParseFlags(args))
+		return searchCriteriaFromFlags(cmd)
+	}
+
+	// At least one criteria is required:
+	_, err := parse()
+	assert.Error(t, err)
+
+	// An empty key still counts (it matches messages without a key):
+	criteria, err := parse("--key", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", *criteria.key)
+
+	criteria, err = parse("--header", "source=web", "--header", "trace=a=b", "--value-regex", "fail(ed)?")
+	assert.NoError(t, err)
+	assert.Nil(t, criteria.key)
+	assert.Equal(t, map[string]string{"source": "web", "trace": "a=b"}, criteria.headers)
+	assert.True(t, criteria.valueRegex.MatchString("failed"))
+
+	// Bad headers and regexes are reported:
+	_, err = parse("--header", "source")
+	assert.Error(t, err)
+	_, err = parse("--value-regex", "(")
+	assert.ErrorContains(t, err, "invalid value regex")
+}
+
+func TestSearchAll(t *testing.T) {
+	ranges := []offsetRange{{partition: 0, end: 3}, {partition: 1, end: 3}, {partition: 2, end: 3}}
+	key := "match"
+
+	// Every partition is searched (partition 2 fails part of the way through, and the others carry on):
+	stats := &searchStats{}
+	matches := searchAll(ranges, 2, &searchCriteria{key: &key}, stats, func(partitionRange offsetRange, callback func(r *record)) error {
+		for offset := partitionRange.start; offset < partitionRange.end; offset++ {
+			if partitionRange.partition == 2 && offset == 1 {
+				return fmt.Errorf("broker went away")
+			}
+			r := &record{Offset: offset, Partition: partitionRange.partition}
+			if offset != 1 {
+				r.Key = key
+			}
+			callback(r)
+		}
+		return nil
+	})
+
+	var found []string
+	for match := range matches {
+		found = append(found, fmt.Sprintf("%d/%d", match.Partition, match.Offset))
+	}
+	sort.Strings(found)
+	assert.Equal(t, []string{"0/0", "0/2", "1/0", "1/2", "2/0"}, found)
+	assert.Equal(t, int64(1), stats.errors)
+	assert.Equal(t, int64(5), stats.matched)
+	assert.Equal(t, int64(7), stats.scanned)
+}
+
+func TestStoppedShort(t *testing.T) {
+	partitionRange := offsetRange{end: 100, partition: 0, start: 10}
+	assert.False(t, partitionRange.stoppedShort(100))
+	assert.True(t, partitionRange.stoppedShort(99))
+	assert.True(t, partitionRange.stoppedShort(10))
+}
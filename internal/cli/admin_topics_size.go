@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Ways to group topic sizes:
+const (
+	sizeByPartition = "partition"
+	sizeByTopic     = "topic"
+)
+
+func (cli *CLI) initAdminTopicsSize() {
+	sizeCommand := cli.adminTopicsSizeCommand()
+	sizeCommand.PersistentFlags().String("by", sizeByTopic, "What to total sizes by [topic, partition]")
+	sizeCommand.PersistentFlags().Float64("max-divergence", 20, "Flag partitions whose largest replica is more than this percentage bigger than their smallest")
+	sizeCommand.PersistentFlags().String("sort", sortBySize, "How to sort [size, name]")
+	cli.SetCommand("adminTopicsSize", "adminTopics", sizeCommand)
+}
+
+// adminTopicsSizeCommand deals with reporting how much disk topics use:
+func (cli *CLI) adminTopicsSizeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "size [topic...]",
+		Short: "Show how much disk every topic (or partition) uses across all of its replicas, flagging replicas whose sizes diverge",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the by flag:
+			by, err := cmd.Flags().GetString("by")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "by").Fatal("Unable to get flag")
+			}
+			if by != sizeByTopic && by != sizeByPartition {
+				cli.logger.WithField("by", by).Fatal("Sizes can be totalled by topic or partition")
+			}
+
+			// Get the sort flag:
+			sortBy, err := cmd.Flags().GetString("sort")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "sort").Fatal("Unable to get flag")
+			}
+			if sortBy != sortBySize && sortBy != sortByName {
+				cli.logger.WithField("sort", sortBy).Fatal("Sizes can be sorted by size or name")
+			}
+
+			// Get the max-divergence flag:
+			maxDivergence, err := cmd.Flags().GetFloat64("max-divergence")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "max-divergence").Fatal("Unable to get flag")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Retrieving topic sizes")
+
+			// Ask every broker how big its replicas are:
+			logDirs, replicas, err := cli.describeLogDirs(args...)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to describe log dirs")
+			}
+			for _, dir := range logDirs {
+				if dir.Error != nil {
+					cli.logger.WithError(dir.Error).WithField("broker", dir.Broker).WithField("log_dir", dir.Path).Warn("Unable to describe a log dir (sizes will be incomplete)")
+				}
+			}
+
+			// Total them up (leaving out replicas which are still being copied between log dirs):
+			current := currentReplicas(replicas)
+			totals := totalSizes(current, func(replica replicaSize) sizeKey {
+				if by == sizeByPartition {
+					return sizeKey{Partition: replica.Partition, Topic: replica.Topic}
+				}
+				return sizeKey{Topic: replica.Topic}
+			})
+			var totalBytes int64
+			for _, key := range sortedSizes(totals, sortBy) {
+				logger := cli.logger.
+					WithField("bytes", totals[key].Bytes).
+					WithField("replicas", totals[key].Replicas).
+					WithField("size", humanBytes(totals[key].Bytes)).
+					WithField("topic", key.Topic)
+				if by == sizeByPartition {
+					logger.WithField("partition", key.Partition).Info("Partition size")
+				} else {
+					logger.Info("Topic size")
+				}
+				totalBytes += totals[key].Bytes
+			}
+
+			// Flag partitions whose replicas are very different sizes:
+			divergences := divergentReplicas(replicas, maxDivergence)
+			for _, divergence := range divergences {
+				sizes := make(map[int]string, len(divergence.Sizes))
+				for broker, bytes := range divergence.Sizes {
+					sizes[broker] = humanBytes(bytes)
+				}
+				cli.logger.
+					WithField("partition", divergence.Partition).
+					WithField("sizes", sizes).
+					WithField("spread_percent", fmt.Sprintf("%.1f", divergence.SpreadPercent)).
+					WithField("topic", divergence.Topic).
+					Warn("Replica sizes diverge")
+			}
+
+			cli.logger.
+				WithField("bytes", totalBytes).
+				WithField("diverging", len(divergences)).
+				WithField("replicas", len(current)).
+				WithField("size", humanBytes(totalBytes)).
+				Info("Total size")
+		},
+	}
+}
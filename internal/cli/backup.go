@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/envelope"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// The manifest describing a backup (written last, so a backup without one is incomplete):
+const backupManifestFile = "manifest.json"
+
+// backupManifest describes a backed-up topic (enough to recreate it):
+type backupManifest struct {
+	Configs           map[string]string `json:"configs"`
+	Created           time.Time         `json:"created"`
+	PartitionCount    int               `json:"partition_count"`
+	Partitions        []backupPartition `json:"partitions"`
+	ReplicationFactor int               `json:"replication_factor"`
+	Topic             string            `json:"topic"`
+}
+
+// backupPartition describes the segment file for one partition (Incomplete is set if reading stopped before the end of its range):
+type backupPartition struct {
+	EndOffset   int64  `json:"end_offset"`
+	File        string `json:"file"`
+	Incomplete  bool   `json:"incomplete,omitempty"`
+	Partition   int    `json:"partition"`
+	Records     int64  `json:"records"`
+	StartOffset int64  `json:"start_offset"`
+}
+
+// loadBackupManifest reads the manifest from a backup directory:
+func loadBackupManifest(dir string) (*backupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &backupManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// incompletePartitions returns the partitions which weren't backed up to the end of their range:
+func (m *backupManifest) incompletePartitions() []int {
+	var partitions []int
+	for _, partition := range m.Partitions {
+		if partition.Incomplete {
+			partitions = append(partitions, partition.Partition)
+		}
+	}
+	return partitions
+}
+
+// save writes the manifest to a backup directory:
+func (m *backupManifest) save(dir string) error {
+	sort.Slice(m.Partitions, func(i, j int) bool {
+		return m.Partitions[i].Partition < m.Partitions[j].Partition
+	})
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, backupManifestFile), data, 0644)
+}
+
+// segmentFileName is the name of the segment file for a partition:
+func segmentFileName(partition int) string {
+	return fmt.Sprintf("partition-%04d.jsonl", partition)
+}
+
+func (cli *CLI) initBackup() {
+	backupCommand := cli.backupCommand()
+	backupCommand.PersistentFlags().String("dir", "", "The directory to write the backup to (required)")
+	backupCommand.PersistentFlags().Duration("idle-timeout", readRangeIdleTimeout, "How long to wait for more messages before giving up on the rest of a partition (the partition is then marked incomplete)")
+	backupCommand.PersistentFlags().Int("workers", 8, "How many partitions to back up at once")
+	backupCommand.MarkPersistentFlagRequired("dir")
+	cli.SetCommand("backup", "root", backupCommand)
+}
+
+// backupCommand deals with backing topics up to local files:
+func (cli *CLI) backupCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:        "backup <topic>",
+		Short:      "Back a topic up to local files (one segment file per partition, and a manifest)",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"topic"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the dir flag:
+			dir, err := cmd.Flags().GetString("dir")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "dir").Fatal("Unable to get flag")
+			}
+
+			// Get the idle-timeout flag:
+			idleTimeout, err := cmd.Flags().GetDuration("idle-timeout")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "idle-timeout").Fatal("Unable to get flag")
+			}
+
+			// Get the workers flag:
+			workers, err := cmd.Flags().GetInt("workers")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "workers").Fatal("Unable to get flag")
+			}
+			if workers < 1 {
+				workers = 1
+			}
+
+			// Get the topic name:
+			topicName := args[0]
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Backing up topic: %s", topicName)
+
+			// Describe the topic:
+			topic, err := cli.topicMetadata(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to describe topic")
+			}
+			configs, err := cli.topicConfigOverrides(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to retrieve topic config")
+			}
+			manifest := &backupManifest{
+				Configs:        configs,
+				Created:        time.Now().UTC(),
+				PartitionCount: len(topic.Partitions),
+				Topic:          topicName,
+			}
+			if len(topic.Partitions) > 0 {
+				manifest.ReplicationFactor = len(topic.Partitions[0].Replicas)
+			}
+
+			// Work out which offsets to back up in each partition (everything up to the high watermarks as they are now):
+			offsetRanges, err := cli.offsetRanges(topicName, time.Time{}, time.Time{})
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to determine which offsets to back up")
+			}
+
+			// Prepare the directory:
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				cli.logger.WithError(err).WithField("dir", dir).Fatal("Unable to create the backup directory")
+			}
+
+			// Periodically report our progress:
+			startTime := time.Now()
+			var messagesWritten int64
+			go func() {
+				for {
+					time.Sleep(time.Second)
+					cli.logger.WithField("messages", atomic.LoadInt64(&messagesWritten)).WithField("messages/s", atomic.LoadInt64(&messagesWritten)/int64(time.Since(startTime).Seconds()+1)).Info("Progress report")
+				}
+			}()
+
+			// Back the partitions up with a bounded number of workers (partitions without anything in range get empty files):
+			pending := make(chan offsetRange, len(topic.Partitions))
+			for _, partition := range topic.Partitions {
+				partitionRange := offsetRange{partition: partition.ID}
+				for _, candidate := range offsetRanges {
+					if candidate.partition == partition.ID {
+						partitionRange = candidate
+					}
+				}
+				pending <- partitionRange
+			}
+			close(pending)
+
+			var failed bool
+			var mutex sync.Mutex
+			var waitGroup sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				waitGroup.Add(1)
+				go func() {
+					defer waitGroup.Done()
+					for partitionRange := range pending {
+						backedUp, err := cli.backupPartition(topicName, partitionRange, idleTimeout, dir, &messagesWritten)
+
+						mutex.Lock()
+						if err != nil {
+							failed = true
+							cli.logger.WithError(err).WithField("partition", partitionRange.partition).Error("Unable to back up partition")
+						} else {
+							manifest.Partitions = append(manifest.Partitions, *backedUp)
+							cli.logger.WithField("partition", backedUp.Partition).WithField("records", backedUp.Records).Debug("Backed up partition")
+						}
+						mutex.Unlock()
+					}
+				}()
+			}
+			waitGroup.Wait()
+
+			if failed {
+				cli.logger.WithField("dir", dir).Fatal("Backup incomplete (no manifest was written)")
+			}
+
+			// Write the manifest:
+			if err := manifest.save(dir); err != nil {
+				cli.logger.WithError(err).WithField("dir", dir).Fatal("Unable to write the backup manifest")
+			}
+
+			// Fail if any partition stopped short (the manifest says which, so restore can refuse them):
+			if incomplete := manifest.incompletePartitions(); len(incomplete) > 0 {
+				cli.logger.WithField("dir", dir).WithField("partitions", incomplete).Fatal("Backup incomplete (some partitions stopped before the end of their range, so restoring needs --allow-incomplete)")
+			}
+
+			cli.logger.
+				WithField("dir", dir).
+				WithField("messages", messagesWritten).
+				WithField("partitions", manifest.PartitionCount).
+				WithField("topic", topicName).
+				Info("Backup complete")
+		},
+	}
+}
+
+// backupPartition writes every message in a partition's range to its segment file (as envelopes, one per line):
+func (cli *CLI) backupPartition(topicName string, partitionRange offsetRange, idleTimeout time.Duration, dir string, messagesWritten *int64) (*backupPartition, error) {
+	backedUp := &backupPartition{
+		EndOffset:   partitionRange.end,
+		File:        segmentFileName(partitionRange.partition),
+		Partition:   partitionRange.partition,
+		StartOffset: partitionRange.start,
+	}
+
+	file, err := os.Create(filepath.Join(dir, backedUp.File))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buffer)
+
+	// Only read partitions with something in range:
+	if partitionRange.end > partitionRange.start {
+		backedUp.EndOffset, err = cli.readRange(topicName, partitionRange, idleTimeout, func(message kafka.Message) error {
+			messageEnvelope := envelope.New(message)
+			messageEnvelope.TimestampType = cli.timestampType(topicName)
+			if err := encoder.Encode(messageEnvelope); err != nil {
+				return err
+			}
+			backedUp.Records++
+			atomic.AddInt64(messagesWritten, 1)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// The manifest records where the backup really got up to:
+		if partitionRange.stoppedShort(backedUp.EndOffset) {
+			backedUp.Incomplete = true
+			cli.logger.
+				WithField("end", partitionRange.end).
+				WithField("partition", partitionRange.partition).
+				WithField("reached", backedUp.EndOffset).
+				Warn("Backup of partition stopped before the end of its range (the rest may only be transaction markers, or the broker is slow)")
+		}
+	}
+
+	if err := buffer.Flush(); err != nil {
+		return nil, err
+	}
+	return backedUp, file.Close()
+}
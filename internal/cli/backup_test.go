@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/envelope"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	testManifest := &backupManifest{
+		Configs:        map[string]string{"cleanup.policy": "compact"},
+		PartitionCount: 2,
+		Partitions: []backupPartition{
+			{EndOffset: 20, File: segmentFileName(1), Partition: 1, Records: 10, StartOffset: 10},
+			{EndOffset: 5, File: segmentFileName(0), Partition: 0, Records: 5},
+			{EndOffset: 7, File: segmentFileName(2), Incomplete: true, Partition: 2, Records: 7},
+		},
+		ReplicationFactor: 3,
+		Topic:             "orders",
+	}
+	assert.NoError(t, testManifest.save(dir), "Error while saving a manifest")
+
+	// Partitions are saved in order:
+	reloaded, err := loadBackupManifest(dir)
+	assert.NoError(t, err, "Error while loading a manifest")
+	assert.Equal(t, "orders", reloaded.Topic)
+	assert.Equal(t, "compact", reloaded.Configs["cleanup.policy"])
+	assert.Equal(t, 3, reloaded.ReplicationFactor)
+	assert.Len(t, reloaded.Partitions, 3)
+	assert.Equal(t, 0, reloaded.Partitions[0].Partition)
+	assert.Equal(t, "partition-0001.jsonl", reloaded.Partitions[1].File)
+
+	// Partitions which stopped short are remembered:
+	assert.Equal(t, []int{2}, reloaded.incompletePartitions())
+}
+
+func TestReadSegment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), segmentFileName(3))
+
+	// Write a segment (as backup does):
+	original := []kafka.Message{
+		{
+			Headers:   []kafka.Header{{Key: "trace", Value: []byte("abc")}, {Key: "trace", Value: []byte{0xff}}},
+			Key:       []byte("order-1"),
+			Offset:    41,
+			Partition: 3,
+			Time:      time.UnixMilli(1700000000123).UTC(),
+			Topic:     "orders",
+			Value:     []byte{0x00, 0xfe},
+		},
+		{
+			Key:       []byte("order-1"),
+			Offset:    42,
+			Partition: 3,
+			Time:      time.UnixMilli(1700000000456).UTC(),
+			Topic:     "orders",
+		},
+	}
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	encoder := json.NewEncoder(file)
+	for _, message := range original {
+		assert.NoError(t, encoder.Encode(envelope.New(message)))
+	}
+	assert.NoError(t, file.Close())
+
+	// Read it back (binary values, repeated headers, tombstones and timestamps survive):
+	var restored []kafka.Message
+	err = readSegment(path, func(message kafka.Message) error {
+		restored = append(restored, message)
+		return nil
+	})
+	assert.NoError(t, err, "Error while reading a segment")
+	assert.Len(t, restored, 2)
+	assert.Equal(t, original[0].Headers, restored[0].Headers)
+	assert.Equal(t, original[0].Value, restored[0].Value)
+	assert.True(t, original[0].Time.Equal(restored[0].Time))
+	assert.Equal(t, int64(42), restored[1].Offset)
+	assert.Nil(t, restored[1].Value)
+
+	// Messages stay on their partition:
+	assert.Equal(t, 3, partitionBalancer{}.Balance(restored[0], 0, 1, 2, 3))
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// checkpoint persists the next offset to read for each partition (so group-less consumers can resume where they stopped):
+type checkpoint struct {
+	dirty     bool
+	mutex     sync.Mutex
+	path      string
+	positions map[string]map[int]int64
+}
+
+// loadCheckpoint reads a checkpoint file (a missing file is an empty checkpoint):
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{
+		path:      path,
+		positions: make(map[string]map[int]int64),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.positions); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// position returns the next offset to read for a partition (if we have one):
+func (c *checkpoint) position(topicName string, partition int) (int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	offset, ok := c.positions[topicName][partition]
+	return offset, ok
+}
+
+// advance records that a message has been dealt with:
+func (c *checkpoint) advance(message kafka.Message) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.positions[message.Topic] == nil {
+		c.positions[message.Topic] = make(map[int]int64)
+	}
+	c.positions[message.Topic][message.Partition] = message.Offset + 1
+	c.dirty = true
+}
+
+// save writes the checkpoint (if anything has changed), replacing the file atomically:
+func (c *checkpoint) save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.positions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryFile.Name())
+
+	if _, err := temporaryFile.Write(data); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err := temporaryFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temporaryFile.Name(), c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
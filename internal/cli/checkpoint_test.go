@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.checkpoint")
+
+	// A missing file is an empty checkpoint:
+	testCheckpoint, err := loadCheckpoint(path)
+	assert.NoError(t, err, "Error while loading a missing checkpoint")
+	_, ok := testCheckpoint.position("orders", 3)
+	assert.False(t, ok)
+
+	// Positions are the offset after the last message dealt with:
+	testCheckpoint.advance(kafka.Message{Topic: "orders", Partition: 3, Offset: 1000})
+	testCheckpoint.advance(kafka.Message{Topic: "orders", Partition: 3, Offset: 1001})
+	testCheckpoint.advance(kafka.Message{Topic: "payments", Partition: 0, Offset: 7})
+	assert.NoError(t, testCheckpoint.save(), "Error while saving a checkpoint")
+
+	// A later run resumes from them:
+	reloaded, err := loadCheckpoint(path)
+	assert.NoError(t, err, "Error while reloading a checkpoint")
+	offset, ok := reloaded.position("orders", 3)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1002), offset)
+	offset, ok = reloaded.position("payments", 0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), offset)
+}
@@ -11,11 +11,13 @@ import (
 
 // CLI contains our dependencies:
 type CLI struct {
-	adminClient *kafka.Client
-	commands    map[string]*cobra.Command
-	config      *configuration.Config
-	logger      *logrus.Logger
-	mutex       sync.Mutex
+	adminClient       *kafka.Client
+	commands          map[string]*cobra.Command
+	config            *configuration.Config
+	logger            *logrus.Logger
+	mutex             sync.Mutex
+	topicConfigs      map[string]map[string]string
+	topicConfigsMutex sync.Mutex
 }
 
 // New returns a configured CLI command:
@@ -29,10 +31,11 @@ func New(config *configuration.Config, logger *logrus.Logger) *CLI {
 
 	// Make a new CLI:
 	c := &CLI{
-		adminClient: adminClient,
-		commands:    make(map[string]*cobra.Command),
-		config:      config,
-		logger:      logger,
+		adminClient:  adminClient,
+		commands:     make(map[string]*cobra.Command),
+		config:       config,
+		logger:       logger,
+		topicConfigs: make(map[string]map[string]string),
 	}
 
 	// Add a root command:
@@ -41,10 +44,20 @@ func New(config *configuration.Config, logger *logrus.Logger) *CLI {
 
 	// Add subcommands:
 	c.initAdmin()
+	c.initAdminBrokers()
 	c.initAdminConfig()
 	c.initAdminGroups()
+	c.initAdminPartitions()
 	c.initAdminTopics()
+	c.initAdminTopicsSearch()
+	c.initAdminTopicsSize()
+	c.initBackup()
 	c.initConsume()
+	c.initDoctor()
+	c.initGet()
+	c.initMirror()
+	c.initReplay()
+	c.initRestore()
 
 	return c
 }
@@ -90,11 +103,19 @@ func (cli *CLI) rootCommand() *cobra.Command {
 		Long: `
 Set these env-vars to configure the CLI:
 
+- KAFKA_ADDRESSMAP: Comma-separated broker address rewrites for port-forwards and tunnels (eg "b-1.internal:9092=localhost:19092") (optional)
 - KAFKA_BOOTSTRAPSERVERS: The Kafka brokers to connect to ("localhost:9092")
 - KAFKA_PASSWORD: The SASL password to authenticate with (optional)
+- KAFKA_PROXYURL: A proxy to tunnel broker connections through ("socks5://host:port" or "http://host:port") (optional)
+- KAFKA_PROXYUSERNAME: The proxy username (overrides any in the proxy URL) (optional)
+- KAFKA_PROXYPASSWORD: The proxy password (overrides any in the proxy URL) (optional)
 - KAFKA_USERNAME: The SASL username to authenticate with (optional)
 - KAFKA_SASLMECHANISM: The mechanism for SASL auth ["SCRAM-SHA-256", "SCRAM-SHA-512" (default)]
 - KAFKA_SECURITYPROTOCOL: The security protocol ["AWS_MSK_IAM, SASL_SSL", "SASL_PLAINTEXT", "SSL", "PLAINTEXT" (default)]
+- <CONTEXT>_KAFKA_*: Any of the KAFKA_* env-vars with a context prefix (eg PROD_KAFKA_BOOTSTRAPSERVERS), for commands which take a context (such as mirror) (optional)
+- SCHEMAREGISTRY_URL: The schema registry for registry-aware formats (optional)
+- SCHEMAREGISTRY_USERNAME / SCHEMAREGISTRY_PASSWORD: Basic-auth for the schema registry (optional)
+- SCHEMAREGISTRY_CACERT / SCHEMAREGISTRY_CERTFILE / SCHEMAREGISTRY_KEYFILE: TLS for the schema registry (optional)
 `,
 		CompletionOptions: cobra.CompletionOptions{
 			DisableDefaultCmd: true,
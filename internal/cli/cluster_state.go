@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+// Leader election types (as the ElectLeaders API numbers them):
+const (
+	electionPreferred int8 = 0
+	electionUnclean   int8 = 1
+)
+
+// electionTypes maps names to leader election types:
+var electionTypes = map[string]int8{
+	"preferred": electionPreferred,
+	"unclean":   electionUnclean,
+}
+
+// clusterState is the controller's view of the brokers and partitions (by broker ID, which kafka-go's metadata loses for brokers that are down):
+type clusterState struct {
+	Brokers    []int
+	Controller int
+	Partitions []partitionState
+	Racks      map[int]string
+}
+
+// partitionState describes one partition (a leader of -1 means it has none):
+type partitionState struct {
+	Error     error
+	Internal  bool
+	Isr       []int
+	Leader    int
+	Offline   []int
+	Partition int
+	Replicas  []int
+	Topic     string
+}
+
+// clusterState retrieves the state of some topics (or every topic when none are given):
+func (cli *CLI) clusterState(topicNames ...string) (*clusterState, error) {
+	request := &metadata.Request{}
+	if len(topicNames) > 0 {
+		request.TopicNames = topicNames
+	}
+
+	response, err := cli.adminClient.Transport.RoundTrip(context.TODO(), cli.adminClient.Addr, request)
+	if err != nil {
+		return nil, err
+	}
+	metadataResponse := response.(*metadata.Response)
+
+	state := &clusterState{
+		Controller: int(metadataResponse.ControllerID),
+		Racks:      make(map[int]string, len(metadataResponse.Brokers)),
+	}
+	for _, broker := range metadataResponse.Brokers {
+		state.Brokers = append(state.Brokers, int(broker.NodeID))
+		state.Racks[int(broker.NodeID)] = broker.Rack
+	}
+	sort.Ints(state.Brokers)
+
+	for _, topic := range metadataResponse.Topics {
+		if topic.ErrorCode != 0 {
+			return nil, fmt.Errorf("unable to describe topic %s: %w", topic.Name, kafka.Error(topic.ErrorCode))
+		}
+		for _, partition := range topic.Partitions {
+			partitionState := partitionState{
+				Internal:  topic.IsInternal,
+				Isr:       brokerIDs(partition.IsrNodes),
+				Leader:    int(partition.LeaderID),
+				Offline:   brokerIDs(partition.OfflineReplicas),
+				Partition: int(partition.PartitionIndex),
+				Replicas:  brokerIDs(partition.ReplicaNodes),
+				Topic:     topic.Name,
+			}
+			if partition.ErrorCode != 0 {
+				partitionState.Error = kafka.Error(partition.ErrorCode)
+			}
+			state.Partitions = append(state.Partitions, partitionState)
+		}
+	}
+	sort.Slice(state.Partitions, func(i, j int) bool {
+		if state.Partitions[i].Topic != state.Partitions[j].Topic {
+			return state.Partitions[i].Topic < state.Partitions[j].Topic
+		}
+		return state.Partitions[i].Partition < state.Partitions[j].Partition
+	})
+
+	return state, nil
+}
+
+// assignments returns the replica sets of the partitions (optionally leaving out internal topics):
+func (cs *clusterState) assignments(includeInternal bool) []partitionAssignment {
+	var assignments []partitionAssignment
+	for _, partition := range cs.Partitions {
+		if partition.Internal && !includeInternal {
+			continue
+		}
+		assignments = append(assignments, partitionAssignment{
+			Partition: partition.Partition,
+			Replicas:  partition.Replicas,
+			Topic:     partition.Topic,
+		})
+	}
+	return assignments
+}
+
+// preferredLeader returns the partition's preferred leader (its first replica), or -1 if it has no replicas:
+func (ps partitionState) preferredLeader() int {
+	if len(ps.Replicas) == 0 {
+		return -1
+	}
+	return ps.Replicas[0]
+}
+
+// brokerIDs converts broker IDs from the protocol:
+func brokerIDs(ids []int32) []int {
+	converted := make([]int, len(ids))
+	for i, id := range ids {
+		converted[i] = int(id)
+	}
+	return converted
+}
+
+// needsElection reports whether a partition needs a leader election (preferred: it isn't led by its preferred replica, unclean: it has no leader):
+func (ps partitionState) needsElection(electionType int8) bool {
+	if electionType == electionUnclean {
+		return ps.Leader < 0
+	}
+	return ps.Leader != ps.preferredLeader()
+}
+
+// leaderCounts counts the partitions each broker leads, and the partitions each broker is the preferred leader of:
+func (cs *clusterState) leaderCounts() (map[int]int, map[int]int) {
+	leaders, preferred := make(map[int]int), make(map[int]int)
+	for _, partition := range cs.Partitions {
+		if partition.Leader >= 0 {
+			leaders[partition.Leader]++
+		}
+		if preferredLeader := partition.preferredLeader(); preferredLeader >= 0 {
+			preferred[preferredLeader]++
+		}
+	}
+	return leaders, preferred
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionStateNeedsElection(t *testing.T) {
+	preferred := partitionState{Isr: []int{1, 2}, Leader: 1, Replicas: []int{1, 2}}
+	moved := partitionState{Isr: []int{2}, Leader: 2, Replicas: []int{1, 2}}
+	leaderless := partitionState{Leader: -1, Offline: []int{1, 2}, Replicas: []int{1, 2}}
+
+	// Preferred elections are needed wherever the leader isn't the first replica:
+	assert.False(t, preferred.needsElection(electionPreferred))
+	assert.True(t, moved.needsElection(electionPreferred))
+	assert.True(t, leaderless.needsElection(electionPreferred))
+
+	// Unclean elections are only needed without a leader:
+	assert.False(t, preferred.needsElection(electionUnclean))
+	assert.False(t, moved.needsElection(electionUnclean))
+	assert.True(t, leaderless.needsElection(electionUnclean))
+}
+
+func TestClusterStateLeaderCounts(t *testing.T) {
+	state := &clusterState{
+		Brokers: []int{1, 2, 3},
+		Partitions: []partitionState{
+			{Leader: 1, Partition: 0, Replicas: []int{1, 2}, Topic: "orders"},
+			{Leader: 1, Partition: 1, Replicas: []int{2, 1}, Topic: "orders"},
+			{Leader: -1, Partition: 2, Replicas: []int{3, 1}, Topic: "orders"},
+		},
+	}
+
+	leaders, preferred := state.leaderCounts()
+	assert.Equal(t, map[int]int{1: 2}, leaders)
+	assert.Equal(t, map[int]int{1: 1, 2: 1, 3: 1}, preferred)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Commit modes which can be chosen with --commit:
+const (
+	commitAfterWrite = "after-write"
+	commitAuto       = "auto"
+	commitNone       = "none"
+	commitOnExit     = "on-exit"
+)
+
+// committer records our progress (to the consumer group or a checkpoint file) according to the commit mode:
+type committer struct {
+	checkpoint *checkpoint
+	consumer   *subscription
+	held       map[string]map[int]bool
+	interval   time.Duration
+	lastSaved  time.Time
+	latest     map[string]map[int]kafka.Message
+	mode       string
+}
+
+// newCommitter returns a committer (the checkpoint is optional):
+func newCommitter(mode string, interval time.Duration, consumer *subscription, checkpoint *checkpoint) *committer {
+	return &committer{
+		checkpoint: checkpoint,
+		consumer:   consumer,
+		held:       make(map[string]map[int]bool),
+		interval:   interval,
+		lastSaved:  time.Now(),
+		latest:     make(map[string]map[int]kafka.Message),
+		mode:       mode,
+	}
+}
+
+// failed records that a message couldn't be written. Offsets can't skip a message, so nothing more is committed for its partition:
+func (c *committer) failed(message kafka.Message) {
+	if c.held[message.Topic] == nil {
+		c.held[message.Topic] = make(map[int]bool)
+	}
+	c.held[message.Topic][message.Partition] = true
+}
+
+// handled records that messages have been dealt with (written, or skipped on purpose):
+func (c *committer) handled(ctx context.Context, messages ...kafka.Message) error {
+	if c.mode == commitNone {
+		return nil
+	}
+
+	// Leave out partitions which are held back by a message we couldn't write:
+	var handled []kafka.Message
+	for _, message := range messages {
+		if !c.held[message.Topic][message.Partition] {
+			handled = append(handled, message)
+		}
+	}
+	messages = handled
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// Checkpoints are saved every interval (unless we're waiting until we exit):
+	if c.checkpoint != nil {
+		for _, message := range messages {
+			c.checkpoint.advance(message)
+		}
+		if c.mode != commitOnExit && time.Since(c.lastSaved) >= c.interval {
+			c.lastSaved = time.Now()
+			return c.checkpoint.save()
+		}
+		return nil
+	}
+
+	switch c.mode {
+	case commitAfterWrite:
+		return c.consumer.Commit(ctx, messages...)
+	case commitOnExit:
+		for _, message := range messages {
+			if c.latest[message.Topic] == nil {
+				c.latest[message.Topic] = make(map[int]kafka.Message)
+			}
+			c.latest[message.Topic][message.Partition] = message
+		}
+	}
+
+	return nil
+}
+
+// close saves the checkpoint, or commits the latest message from each partition if we were waiting until we exit:
+func (c *committer) close(ctx context.Context) error {
+	if c.mode == commitNone {
+		return nil
+	}
+
+	if c.checkpoint != nil {
+		return c.checkpoint.save()
+	}
+
+	var messages []kafka.Message
+	for _, partitions := range c.latest {
+		for _, message := range partitions {
+			messages = append(messages, message)
+		}
+	}
+	return c.consumer.Commit(ctx, messages...)
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitterFailed(t *testing.T) {
+	testCheckpoint, err := loadCheckpoint(filepath.Join(t.TempDir(), "orders.checkpoint"))
+	assert.NoError(t, err, "Error while loading a missing checkpoint")
+	testCommitter := newCommitter(commitAfterWrite, time.Hour, nil, testCheckpoint)
+
+	// A message which couldn't be written holds its partition back (but not the others):
+	assert.NoError(t, testCommitter.handled(context.Background(), kafka.Message{Topic: "orders", Partition: 0, Offset: 10}))
+	testCommitter.failed(kafka.Message{Topic: "orders", Partition: 0, Offset: 11})
+	assert.NoError(t, testCommitter.handled(context.Background(), kafka.Message{Topic: "orders", Partition: 0, Offset: 12}))
+	assert.NoError(t, testCommitter.handled(context.Background(), kafka.Message{Topic: "orders", Partition: 1, Offset: 5}))
+
+	offset, ok := testCheckpoint.position("orders", 0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(11), offset)
+	offset, ok = testCheckpoint.position("orders", 1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(6), offset)
+}
@@ -2,15 +2,37 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
 	"time"
 
+	"github.com/chrusty/kafka-cli/internal/configuration"
+	"github.com/chrusty/kafka-cli/internal/serdes"
+	"github.com/segmentio/kafka-go"
 	"github.com/spf13/cobra"
 )
 
 func (cli *CLI) initConsume() {
 	consumeCommand := cli.consumeCommand()
+	consumeCommand.PersistentFlags().String("filter", "", `Only print messages matching an expression (eg 'key == "abc" && value.status == "FAILED" && headers["tenant"] == "x"')`)
+	consumeCommand.PersistentFlags().Int("count", 0, "Stop after printing this many messages (0 for no limit)")
+	consumeCommand.PersistentFlags().Int64("offset", kafka.FirstOffset, "Offset to start from with --partition (-2 for the earliest, -1 for the latest)")
+	consumeCommand.PersistentFlags().Int("partition", -1, "Only consume this partition (without a consumer group)")
+	consumeCommand.PersistentFlags().String("topic-regex", "", "Also consume every topic matching this regular expression")
+	consumeCommand.PersistentFlags().Duration("topic-refresh", time.Minute, "How often to look for new topics (and partitions)")
+	consumeCommand.PersistentFlags().Bool("fail-on-invalid", false, "Exit non-zero as soon as a message fails schema validation")
+	consumeCommand.PersistentFlags().String("isolation", isolationReadUncommitted, "Whether to read messages from aborted (or still open) transactions [read_committed, read_uncommitted]")
+	consumeCommand.PersistentFlags().Bool("show-transactions", false, "With read_uncommitted (and no group), mark messages from aborted transactions and show transaction markers")
+	consumeCommand.PersistentFlags().String("checkpoint-file", "", "Without a group, resume from (and save) the position of each partition in this file")
+	consumeCommand.PersistentFlags().String("commit", commitAuto, "When to commit offsets (or save the checkpoint file) [auto, after-write, on-exit, none]")
+	consumeCommand.PersistentFlags().Duration("commit-interval", 5*time.Second, "How often to commit offsets (or save the checkpoint file), 0 for after every message")
 	consumeCommand.PersistentFlags().String("groupid", "", "Consumer group ID (if blank then groups won't be used, offsets won't be committed)")
+	addDeserializerFlags(consumeCommand)
+	addOutputFlags(consumeCommand)
 	cli.SetCommand("consume", "root", consumeCommand)
 }
 
@@ -18,9 +40,8 @@ func (cli *CLI) initConsume() {
 func (cli *CLI) consumeCommand() *cobra.Command {
 
 	return &cobra.Command{
-		Use:        "consume <topic>",
-		Short:      "Consume messages from a topic",
-		Args:       cobra.ExactArgs(1),
+		Use:        "consume [topic...]",
+		Short:      "Consume messages from one or more topics",
 		ArgAliases: []string{"topic"},
 		Run: func(cmd *cobra.Command, args []string) {
 
@@ -30,8 +51,124 @@ func (cli *CLI) consumeCommand() *cobra.Command {
 				cli.logger.WithError(err).WithField("flag", "groupid").Fatal("Unable to get flag")
 			}
 
-			// Get the topic name:
-			topicName := args[0]
+			// Get the failOnInvalid flag:
+			failOnInvalid, err := cmd.Flags().GetBool("fail-on-invalid")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "fail-on-invalid").Fatal("Unable to get flag")
+			}
+
+			// Get the partition, offset and count flags:
+			partition, err := cmd.Flags().GetInt("partition")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "partition").Fatal("Unable to get flag")
+			}
+			offset, err := cmd.Flags().GetInt64("offset")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "offset").Fatal("Unable to get flag")
+			}
+			count, err := cmd.Flags().GetInt("count")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "count").Fatal("Unable to get flag")
+			}
+
+			// Get the commit flags:
+			commitMode, err := cmd.Flags().GetString("commit")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "commit").Fatal("Unable to get flag")
+			}
+			switch commitMode {
+			case commitAfterWrite, commitAuto, commitNone, commitOnExit:
+			default:
+				cli.logger.WithField("commit", commitMode).Fatal("Unknown commit mode")
+			}
+			commitInterval, err := cmd.Flags().GetDuration("commit-interval")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "commit-interval").Fatal("Unable to get flag")
+			}
+			checkpointFile, err := cmd.Flags().GetString("checkpoint-file")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "checkpoint-file").Fatal("Unable to get flag")
+			}
+			var consumerCheckpoint *checkpoint
+			if checkpointFile != "" {
+				if groupId != "" {
+					cli.logger.Fatal("--checkpoint-file can't be used with a consumer group")
+				}
+				if consumerCheckpoint, err = loadCheckpoint(checkpointFile); err != nil {
+					cli.logger.WithError(err).WithField("file", checkpointFile).Fatal("Unable to load the checkpoint file")
+				}
+			}
+
+			// Get the isolation flags:
+			isolation, err := cmd.Flags().GetString("isolation")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "isolation").Fatal("Unable to get flag")
+			}
+			showTransactions, err := cmd.Flags().GetBool("show-transactions")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "show-transactions").Fatal("Unable to get flag")
+			}
+			readerOptions := configuration.ReaderOptions{CommitInterval: commitInterval}
+			switch isolation {
+			case isolationReadCommitted:
+				readerOptions.IsolationLevel = kafka.ReadCommitted
+			case isolationReadUncommitted:
+				readerOptions.IsolationLevel = kafka.ReadUncommitted
+			default:
+				cli.logger.WithField("isolation", isolation).Fatal("Unknown isolation level")
+			}
+			if showTransactions && (isolation != isolationReadUncommitted || groupId != "") {
+				cli.logger.Fatal("--show-transactions needs --isolation read_uncommitted (and can't be used with a consumer group)")
+			}
+
+			// Get the topic regex flags:
+			topicRegexFlag, err := cmd.Flags().GetString("topic-regex")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topic-regex").Fatal("Unable to get flag")
+			}
+			var topicRegex *regexp.Regexp
+			if topicRegexFlag != "" {
+				if topicRegex, err = regexp.Compile(topicRegexFlag); err != nil {
+					cli.logger.WithError(err).WithField("flag", "topic-regex").Fatal("Invalid regex")
+				}
+			}
+			topicRefresh, err := cmd.Flags().GetDuration("topic-refresh")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topic-refresh").Fatal("Unable to get flag")
+			}
+
+			// Get the filter flag:
+			filterExpression, err := cmd.Flags().GetString("filter")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "filter").Fatal("Unable to get flag")
+			}
+			filter, err := newMessageFilter(filterExpression)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a filter")
+			}
+
+			// Get deserializers for the message keys, values and headers:
+			deserializers, err := cli.messageDeserializers(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare deserializers")
+			}
+
+			// Get a formatter for the output:
+			formatter, err := cli.recordFormatter(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare an output formatter")
+			}
+
+			// Get the topic names (and check they go with the partition and offset):
+			topicNames := args
+			if err := checkConsumeTargets(topicNames, topicRegex, groupId, partition, offset, cmd.Flags().Changed("offset")); err != nil {
+				cli.logger.WithError(err).Fatal("Invalid topics, partition or offset")
+			}
+
+			// Prefix plain output with the topic name when there could be more than one:
+			if valueFormatter, ok := formatter.(*valueFormatter); ok {
+				valueFormatter.withTopic = len(topicNames) > 1 || topicRegex != nil
+			}
 
 			// Config:
 			cli.logger.
@@ -39,29 +176,59 @@ func (cli *CLI) consumeCommand() *cobra.Command {
 				WithField("security", cli.config.Kafka.SecurityProtocol).
 				WithField("servers", cli.config.Kafka.BootstrapServers).
 				WithField("username", cli.config.Kafka.Username).
-				Debugf("Consuming topic: %s", topicName)
+				Debugf("Consuming topics: %v (regex: %s)", topicNames, topicRegexFlag)
 
-			// Get an admin client:
-			consumer, err := cli.config.Kafka.Consumer(cli.logger, groupId, topicName)
+			// Subscribe to the topics (either for a specific partition, or all of them), until we're interrupted:
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			consumer, err := cli.subscribe(ctx, subscriptionOptions{
+				autoCommit:       commitMode == commitAuto,
+				checkpoint:       consumerCheckpoint,
+				groupId:          groupId,
+				offset:           offset,
+				partition:        partition,
+				readerOptions:    readerOptions,
+				refreshInterval:  topicRefresh,
+				showTransactions: showTransactions,
+				topicNames:       topicNames,
+				topicRegex:       topicRegex,
+			})
 			if err != nil {
-				cli.logger.WithError(err).WithField("topic", topicName).WithField("group", groupId).Fatal("Unable to prepare a consumer")
+				cli.logger.WithError(err).WithField("topics", topicNames).WithField("group", groupId).WithField("partition", partition).Fatal("Unable to prepare a consumer")
 			}
+			defer consumer.Close()
+			committer := newCommitter(commitMode, commitInterval, consumer, consumerCheckpoint)
 
 			// Periodically report our progress:
 			startTime := time.Now()
-			var totalErrors, messagesConsumed int32
+			var totalErrors, totalInvalid, messagesConsumed, messagesMatched int32
 			go func() {
 				for {
 					time.Sleep(time.Second)
-					cli.logger.WithField("errors", totalErrors).WithField("messages", messagesConsumed).WithField("messages/s", messagesConsumed/int32(time.Since(startTime).Seconds())).Info("Progress report")
+					cli.logger.WithField("errors", totalErrors).WithField("invalid", totalInvalid).WithField("matched", messagesMatched).WithField("scanned", messagesConsumed).WithField("messages/s", messagesConsumed/int32(time.Since(startTime).Seconds())).Info("Progress report")
 				}
 			}()
 
 			// Consume:
+			var previous *kafka.Message
+			var stoppedOnInvalid bool
 			for {
 
-				// Get a message:
-				message, err := consumer.ReadMessage(context.TODO())
+				// Record that the previous message was dealt with (messages which couldn't be written never are):
+				if previous != nil {
+					if err := committer.handled(ctx, *previous); err != nil {
+						cli.logger.WithError(err).WithField("offset", previous.Offset).WithField("partition", previous.Partition).Error("Unable to commit")
+						totalErrors++
+					}
+					previous = nil
+				}
+
+				// Get a message (stopping if we've been interrupted):
+				consumed, err := consumer.ReadMessage(ctx)
+				message := consumed.message
+				if ctx.Err() != nil {
+					break
+				}
 				if err != nil {
 					cli.logger.WithError(err).Error("Unable to consume a message")
 					totalErrors++
@@ -69,17 +236,101 @@ func (cli *CLI) consumeCommand() *cobra.Command {
 				}
 				messagesConsumed++
 
+				// Decode the message:
+				decoded, err := deserializers.record(message)
+				var validationErr *serdes.ValidationError
+				switch {
+				case errors.As(err, &validationErr):
+					totalInvalid++
+					cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Warn("Message failed schema validation")
+				case err != nil:
+					cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Error("Unable to deserialize a message")
+					totalErrors++
+					committer.failed(message)
+					continue
+				}
+
+				// Look up the timestamp type (which Kafka only records per topic):
+				decoded.TimestampType = cli.timestampType(message.Topic)
+				decoded.Transaction = consumed.transaction
+
+				// Skip messages which don't match our filter:
+				matched, err := filter.match(decoded)
+				if err != nil {
+					cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Debug("Unable to evaluate the filter")
+				}
+				if !matched {
+					previous = &message
+					continue
+				}
+				messagesMatched++
+
 				// Log the message metadata:
 				cli.logger.
-					WithField("headers", message.Headers).
-					WithField("key", string(message.Key)).
+					WithField("headers", decoded.Headers).
+					WithField("key", decoded.Key).
 					WithField("offset", message.Offset).
 					WithField("partition", message.Partition).
 					Debug("Got a message")
 
-				// Print the payload:
-				fmt.Println(string(message.Value))
+				// Print the message:
+				output, err := formatter.format(decoded)
+				if err != nil {
+					cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Error("Unable to format a message")
+					totalErrors++
+					committer.failed(message)
+					continue
+				}
+				fmt.Println(output)
+				previous = &message
+
+				// Stop if we've been asked to fail on invalid messages (exiting once we've committed):
+				if validationErr != nil && failOnInvalid {
+					stoppedOnInvalid = true
+					break
+				}
+
+				// Stop once we've printed as many messages as we were asked for:
+				if count > 0 && int(messagesMatched) >= count {
+					cli.logger.WithField("matched", messagesMatched).WithField("scanned", messagesConsumed).Debug("Consumed the requested number of messages")
+					break
+				}
+			}
+
+			// Commit (or save the checkpoint) on the way out:
+			commitCtx, commitCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer commitCancel()
+			if previous != nil {
+				if err := committer.handled(commitCtx, *previous); err != nil {
+					cli.logger.WithError(err).WithField("offset", previous.Offset).WithField("partition", previous.Partition).Error("Unable to commit")
+				}
+			}
+			if err := committer.close(commitCtx); err != nil {
+				cli.logger.WithError(err).Error("Unable to commit on exit")
+			}
+
+			// Exit non-zero if we stopped on an invalid message (leaving the group first, because deferred calls won't run):
+			if stoppedOnInvalid {
+				consumer.Close()
+				cli.logger.WithField("invalid", totalInvalid).Fatal("Stopped on invalid message")
 			}
 		},
 	}
 }
+
+// checkConsumeTargets makes sure the topics, partition and offset we were given make sense together:
+func checkConsumeTargets(topicNames []string, topicRegex *regexp.Regexp, groupId string, partition int, offset int64, offsetSet bool) error {
+	switch {
+	case len(topicNames) == 0 && topicRegex == nil:
+		return fmt.Errorf("give at least one topic (or --topic-regex)")
+	case partition >= 0 && groupId != "":
+		return fmt.Errorf("--partition can't be used with a consumer group")
+	case partition >= 0 && (len(topicNames) != 1 || topicRegex != nil):
+		return fmt.Errorf("--partition can only be used with a single topic")
+	case partition < 0 && offsetSet:
+		return fmt.Errorf("--offset can only be used with --partition")
+	case offset < kafka.FirstOffset:
+		return fmt.Errorf("--offset must be an offset, -2 (the earliest) or -1 (the latest), not %d", offset)
+	}
+	return nil
+}
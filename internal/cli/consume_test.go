@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConsumeTargets(t *testing.T) {
+	orders := []string{"orders"}
+	regex := regexp.MustCompile(`^orders\.`)
+
+	// Topics, a regex, or both:
+	assert.NoError(t, checkConsumeTargets(orders, nil, "", -1, kafka.FirstOffset, false))
+	assert.NoError(t, checkConsumeTargets(nil, regex, "", -1, kafka.FirstOffset, false))
+	assert.NoError(t, checkConsumeTargets([]string{"orders", "payments"}, regex, "group", -1, kafka.FirstOffset, false))
+	assert.Error(t, checkConsumeTargets(nil, nil, "", -1, kafka.FirstOffset, false))
+
+	// A partition (and an offset in it) needs a single topic and no group:
+	assert.NoError(t, checkConsumeTargets(orders, nil, "", 3, 1000, true))
+	assert.NoError(t, checkConsumeTargets(orders, nil, "", 3, kafka.LastOffset, true))
+	assert.ErrorContains(t, checkConsumeTargets(orders, nil, "group", 3, kafka.FirstOffset, false), "consumer group")
+	assert.ErrorContains(t, checkConsumeTargets([]string{"orders", "payments"}, nil, "", 3, kafka.FirstOffset, false), "single topic")
+	assert.ErrorContains(t, checkConsumeTargets(orders, regex, "", 3, kafka.FirstOffset, false), "single topic")
+
+	// An offset needs a partition, and has to be real (or one of the special ones):
+	assert.ErrorContains(t, checkConsumeTargets(orders, nil, "", -1, 1000, true), "--offset can only be used with --partition")
+	assert.ErrorContains(t, checkConsumeTargets(orders, nil, "", 3, -3, true), "-3")
+}
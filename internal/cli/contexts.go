@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"github.com/chrusty/kafka-cli/internal/configuration"
+)
+
+// forContext returns a CLI for another cluster, configured from a named context's env-vars (a blank name is this CLI):
+func (cli *CLI) forContext(name string) (*CLI, error) {
+	if name == "" {
+		return cli, nil
+	}
+
+	// Load the context's Kafka config:
+	kafkaConfig, err := configuration.KafkaContext(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get an admin client for it:
+	adminClient, err := kafkaConfig.Admin(cli.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything else (logging, the schema registry) is shared:
+	config := *cli.config
+	config.Kafka = *kafkaConfig
+
+	return &CLI{
+		adminClient:  adminClient,
+		commands:     cli.commands,
+		config:       &config,
+		logger:       cli.logger,
+		topicConfigs: make(map[string]map[string]string),
+	}, nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/chrusty/kafka-cli/internal/protocol/deleterecords"
+	"github.com/segmentio/kafka-go"
+)
+
+// How long partition leaders get to delete records:
+const deleteRecordsTimeoutMs = 30000
+
+// deleteRecords deletes every record before an offset in each partition (kafka.LastOffset for everything), returning the new low watermarks:
+func (cli *CLI) deleteRecords(topicName string, offsets map[int]int64) (map[int]int64, error) {
+	requestTopic := deleterecords.RequestTopic{Name: topicName}
+	for partition, offset := range offsets {
+		requestTopic.Partitions = append(requestTopic.Partitions, deleterecords.RequestPartition{
+			PartitionIndex: int32(partition),
+			Offset:         offset,
+		})
+	}
+
+	response, err := cli.adminClient.Transport.RoundTrip(context.TODO(), cli.adminClient.Addr, &deleterecords.Request{
+		Topics:    []deleterecords.RequestTopic{requestTopic},
+		TimeoutMs: deleteRecordsTimeoutMs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Partitions which failed are left out of the low watermarks:
+	lowWatermarks := make(map[int]int64, len(offsets))
+	var partitionErrors []error
+	for _, topic := range response.(*deleterecords.Response).Topics {
+		for _, partition := range topic.Partitions {
+			if partition.ErrorCode != 0 {
+				partitionErrors = append(partitionErrors, fmt.Errorf("unable to delete records from partition %d: %w", partition.PartitionIndex, kafka.Error(partition.ErrorCode)))
+				continue
+			}
+			lowWatermarks[int(partition.PartitionIndex)] = partition.LowWatermark
+		}
+	}
+
+	return lowWatermarks, errors.Join(partitionErrors...)
+}
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"github.com/chrusty/kafka-cli/internal/serdes"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// messageDeserializers decode the various parts of a message:
+type messageDeserializers struct {
+	defaultHeader serdes.Deserializer
+	headers       map[string]serdes.Deserializer
+	key           serdes.Deserializer
+	value         serdes.Deserializer
+}
+
+// addDeserializerFlags adds the flags which choose how messages are decoded:
+func addDeserializerFlags(cmd *cobra.Command) {
+	formats := strings.Join(serdes.Formats(), ", ")
+	cmd.PersistentFlags().StringArray("header-format", nil, fmt.Sprintf("How to decode a specific header, as name=format (repeatable) [%s]", formats))
+	cmd.PersistentFlags().String("key-format", serdes.FormatString, fmt.Sprintf("How to decode message keys [%s]", formats))
+	cmd.PersistentFlags().String("proto-descriptor-set", "", "FileDescriptorSet to find the protobuf message type in (eg from protoc --descriptor_set_out)")
+	cmd.PersistentFlags().StringSlice("proto-file", nil, "The .proto files to find the protobuf message type in")
+	cmd.PersistentFlags().StringSlice("proto-import-path", nil, "Import paths for the .proto files")
+	cmd.PersistentFlags().String("proto-message", "", "Fully-qualified protobuf message type (if blank then schemas come from the registry)")
+	cmd.PersistentFlags().String("schema-registry-url", "", "Schema registry URL (overrides SCHEMAREGISTRY_URL)")
+	cmd.PersistentFlags().String("value-format", serdes.FormatString, fmt.Sprintf("How to decode message values [%s]", formats))
+}
+
+// messageDeserializers prepares deserializers for the formats given in our flags:
+func (cli *CLI) messageDeserializers(cmd *cobra.Command) (*messageDeserializers, error) {
+
+	// Get the options shared by every deserializer:
+	options, err := cli.deserializerOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the format flags:
+	keyFormat, err := cmd.Flags().GetString("key-format")
+	if err != nil {
+		return nil, err
+	}
+	valueFormat, err := cmd.Flags().GetString("value-format")
+	if err != nil {
+		return nil, err
+	}
+	headerFormats, err := cmd.Flags().GetStringArray("header-format")
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare the deserializers:
+	deserializers := &messageDeserializers{
+		defaultHeader: &serdes.String{},
+		headers:       make(map[string]serdes.Deserializer),
+	}
+	if deserializers.key, err = serdes.New(keyFormat, options); err != nil {
+		return nil, fmt.Errorf("unable to prepare a key deserializer: %w", err)
+	}
+	if deserializers.value, err = serdes.New(valueFormat, options); err != nil {
+		return nil, fmt.Errorf("unable to prepare a value deserializer: %w", err)
+	}
+	for _, headerFormat := range headerFormats {
+		name, format, ok := strings.Cut(headerFormat, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header format %q (expected name=format)", headerFormat)
+		}
+		if deserializers.headers[name], err = serdes.New(format, options); err != nil {
+			return nil, fmt.Errorf("unable to prepare a deserializer for header %s: %w", name, err)
+		}
+	}
+
+	return deserializers, nil
+}
+
+// deserializerOptions gathers the flags which configure deserializers (sharing one schema registry client between them):
+func (cli *CLI) deserializerOptions(cmd *cobra.Command) (*serdes.Options, error) {
+	options := &serdes.Options{}
+	var err error
+
+	// Get the protobuf flags:
+	if options.ProtoDescriptorSet, err = cmd.Flags().GetString("proto-descriptor-set"); err != nil {
+		return nil, err
+	}
+	if options.ProtoFiles, err = cmd.Flags().GetStringSlice("proto-file"); err != nil {
+		return nil, err
+	}
+	if options.ProtoImportPaths, err = cmd.Flags().GetStringSlice("proto-import-path"); err != nil {
+		return nil, err
+	}
+	if options.ProtoMessage, err = cmd.Flags().GetString("proto-message"); err != nil {
+		return nil, err
+	}
+
+	// Get the registry URL flag (which overrides the configured URL):
+	registryURL, err := cmd.Flags().GetString("schema-registry-url")
+	if err != nil {
+		return nil, err
+	}
+	registryConfig := cli.config.SchemaRegistry
+	if registryURL != "" {
+		registryConfig.URL = registryURL
+	}
+
+	// Only prepare a registry client if a format asks for one:
+	var registry *schemaregistry.Client
+	var registryErr error
+	var registryOnce sync.Once
+	options.SchemaRegistry = func() (*schemaregistry.Client, error) {
+		registryOnce.Do(func() {
+			registry, registryErr = registryConfig.Client()
+		})
+		return registry, registryErr
+	}
+
+	return options, nil
+}
+
+// deserializeKey renders a message key (falling back to the raw key if it can't be decoded):
+func (md *messageDeserializers) deserializeKey(key []byte) string {
+	deserialized, err := md.key.Deserialize(key)
+	if err != nil {
+		return string(key)
+	}
+	return deserialized
+}
+
+// deserializeHeaders renders message headers (falling back to the raw values of any which can't be decoded):
+func (md *messageDeserializers) deserializeHeaders(headers []kafka.Header) map[string]string {
+	deserialized := make(map[string]string, len(headers))
+
+	for _, header := range headers {
+		deserializer, ok := md.headers[header.Key]
+		if !ok {
+			deserializer = md.defaultHeader
+		}
+
+		value, err := deserializer.Deserialize(header.Value)
+		if err != nil {
+			value = string(header.Value)
+		}
+		deserialized[header.Key] = value
+	}
+
+	return deserialized
+}
+
+// record decodes a message (if the value fails schema validation then the record still comes back, with its violations):
+func (md *messageDeserializers) record(message kafka.Message) (*record, error) {
+
+	// Decode the key (failing like a value which can't be decoded):
+	key, err := md.key.Deserialize(message.Key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the key: %w", err)
+	}
+
+	decoded := &record{
+		Headers:   md.deserializeHeaders(message.Headers),
+		Key:       key,
+		Offset:    message.Offset,
+		Partition: message.Partition,
+		Timestamp: message.Time,
+		Topic:     message.Topic,
+		message:   message,
+	}
+
+	// Decode the value:
+	value, err := md.value.Deserialize(message.Value)
+	var validationErr *serdes.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		decoded.Violations = validationErr.Violations
+	case err != nil:
+		return nil, err
+	}
+	decoded.Value = value
+
+	return decoded, err
+}
+
+// rawRecord renders a message without decoding its value, or its key if that can't be decoded (for messages which can't be deserialized):
+func (md *messageDeserializers) rawRecord(message kafka.Message) *record {
+	return &record{
+		Headers:   md.deserializeHeaders(message.Headers),
+		Key:       md.deserializeKey(message.Key),
+		Offset:    message.Offset,
+		Partition: message.Partition,
+		Timestamp: message.Time,
+		Topic:     message.Topic,
+		Value:     string(message.Value),
+		message:   message,
+	}
+}
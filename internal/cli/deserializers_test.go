@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/chrusty/kafka-cli/internal/serdes"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	deserializers := &messageDeserializers{
+		defaultHeader: &serdes.String{},
+		key:           &serdes.Int64{},
+		value:         &serdes.String{},
+	}
+
+	// Keys which decode are rendered:
+	decoded, err := deserializers.record(kafka.Message{Key: []byte{0, 0, 0, 0, 0, 0, 0, 42}, Value: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", decoded.Key)
+	assert.Equal(t, "hello", decoded.Value)
+
+	// Keys which don't are reported like values which don't (and the raw record keeps the raw key):
+	message := kafka.Message{Key: []byte("abc"), Value: []byte("hello")}
+	decoded, err = deserializers.record(message)
+	assert.ErrorContains(t, err, "unable to decode the key")
+	assert.Nil(t, decoded)
+	assert.Equal(t, "abc", deserializers.rawRecord(message).Key)
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// certificateExpiryWarning is how close to expiry a certificate can get before we complain:
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+func (cli *CLI) initDoctor() {
+	doctorCommand := cli.doctorCommand()
+	doctorCommand.PersistentFlags().Duration("timeout", 10*time.Second, "Timeout for each individual check")
+	cli.SetCommand("doctor", "root", doctorCommand)
+}
+
+// doctorCommand diagnoses connectivity, auth and TLS for every broker:
+func (cli *CLI) doctorCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose connectivity, auth and TLS for each broker",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the timeout flag:
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "timeout").Fatal("Unable to get flag")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debug("Diagnosing cluster connectivity")
+
+			// Prepare a dialer (with our custom auth settings):
+			dialer, err := cli.config.Kafka.Dialer(cli.logger)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a Kafka dialer")
+			}
+			dialer.Timeout = timeout
+
+			// Check each of the bootstrap servers, hanging on to the first good connection:
+			var metadataConn *kafka.Conn
+			var problems int
+			for _, address := range cli.config.Kafka.BootstrapServers {
+				conn, ok := cli.doctorCheckBroker(dialer, "bootstrap", address, timeout)
+				if !ok {
+					problems++
+					continue
+				}
+				if metadataConn == nil {
+					metadataConn = conn
+					continue
+				}
+				conn.Close()
+			}
+
+			// We can't go any further without at least one working bootstrap server:
+			if metadataConn == nil {
+				cli.logger.WithField("problems", problems).Fatal("None of the bootstrap servers are usable")
+			}
+			defer metadataConn.Close()
+
+			// Retrieve the advertised brokers:
+			brokers, err := metadataConn.Brokers()
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to retrieve cluster metadata")
+			}
+			cli.logger.WithField("brokers", len(brokers)).Info("Retrieved advertised brokers from metadata")
+
+			// Now check each of the advertised brokers:
+			for _, broker := range brokers {
+				address := net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port))
+				conn, ok := cli.doctorCheckBroker(dialer, fmt.Sprintf("advertised:%d", broker.ID), address, timeout)
+				if !ok {
+					problems++
+					cli.logger.
+						WithField("address", address).
+						WithField("id", broker.ID).
+						WithField("rack", broker.Rack).
+						Error("Advertised listener is not reachable (the bootstrap servers work, but this broker can't be used from here)")
+					continue
+				}
+				conn.Close()
+			}
+
+			// Summarise:
+			if problems > 0 {
+				cli.logger.WithField("problems", problems).Fatal("Diagnosis found problems")
+			}
+			cli.logger.Info("Diagnosis found no problems")
+		},
+	}
+}
+
+// doctorCheckBroker runs each of the checks against a broker, returning an authenticated connection if they all pass:
+func (cli *CLI) doctorCheckBroker(dialer *kafka.Dialer, role, address string, timeout time.Duration) (*kafka.Conn, bool) {
+	logger := cli.logger.WithField("role", role).WithField("address", address)
+
+	// Split the address:
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		logger.WithError(err).WithField("check", "address").Error("Invalid broker address")
+		return nil, false
+	}
+
+	// Apply our address map (TLS still expects the advertised hostname):
+	dialAddress, err := cli.config.Kafka.RewriteAddress(address)
+	if err != nil {
+		logger.WithError(err).WithField("check", "address").Error("Unable to rewrite broker address")
+		return nil, false
+	}
+	if dialAddress != address {
+		logger = logger.WithField("rewritten", dialAddress)
+		logger.WithField("check", "address").Info("Broker address rewritten")
+	}
+	dialHost, _, err := net.SplitHostPort(dialAddress)
+	if err != nil {
+		logger.WithError(err).WithField("check", "address").Error("Invalid rewritten broker address")
+		return nil, false
+	}
+
+	// Each check gets the whole timeout to itself:
+	checkContext := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+
+	// DNS (proxies resolve hostnames themselves):
+	if cli.config.Kafka.ProxyURL != "" {
+		logger.WithField("check", "dns").WithField("proxy", cli.config.Kafka.ProxyURL).Info("Skipping DNS (the proxy will resolve broker hostnames)")
+	} else {
+		ctx, cancel := checkContext()
+		resolved, err := net.DefaultResolver.LookupHost(ctx, dialHost)
+		cancel()
+		if err != nil {
+			logger.WithError(err).WithField("check", "dns").Error("Unable to resolve broker hostname")
+			return nil, false
+		}
+		logger.WithField("check", "dns").WithField("resolved", resolved).Info("Resolved broker hostname")
+	}
+
+	// TCP (through our address map and proxy):
+	dialFunc, err := cli.config.Kafka.DialFunc()
+	if err != nil {
+		logger.WithError(err).WithField("check", "tcp").Error("Unable to prepare a dial function")
+		return nil, false
+	}
+	startTime := time.Now()
+	ctx, cancel := checkContext()
+	tcpConn, err := dialFunc(ctx, "tcp", address)
+	cancel()
+	if err != nil {
+		logger.WithError(err).WithField("check", "tcp").Error("Unable to connect to broker")
+		return nil, false
+	}
+	logger.WithField("check", "tcp").WithField("latency", time.Since(startTime).String()).Info("Connected to broker")
+
+	// TLS:
+	if dialer.TLS != nil {
+		tlsConfig := dialer.TLS.Clone()
+		tlsConfig.ServerName = host
+		tlsConn := tls.Client(tcpConn, tlsConfig)
+		ctx, cancel := checkContext()
+		err := tlsConn.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			tcpConn.Close()
+			logger.WithError(err).WithField("check", "tls").Error("TLS handshake failed")
+			return nil, false
+		}
+		cli.doctorReportCertificates(logger.WithField("check", "tls"), tlsConn.ConnectionState())
+	}
+	tcpConn.Close()
+
+	// SASL (the Kafka dialer does TLS and SASL for us):
+	ctx, cancel = checkContext()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	cancel()
+	if err != nil {
+		if dialer.SASLMechanism != nil {
+			logger.WithError(err).WithField("check", "sasl").WithField("mechanism", dialer.SASLMechanism.Name()).Error("SASL authentication failed")
+		} else {
+			logger.WithError(err).WithField("check", "kafka").Error("Unable to establish a Kafka connection")
+		}
+		return nil, false
+	}
+	if dialer.SASLMechanism != nil {
+		logger.WithField("check", "sasl").WithField("mechanism", dialer.SASLMechanism.Name()).Info("SASL authentication succeeded")
+	}
+
+	// ApiVersions:
+	conn.SetDeadline(time.Now().Add(timeout))
+	apiVersions, err := conn.ApiVersions()
+	if err != nil {
+		conn.Close()
+		logger.WithError(err).WithField("check", "apiversions").Error("Unable to fetch API versions")
+		return nil, false
+	}
+	for _, apiVersion := range apiVersions {
+		logger.
+			WithField("api_key", apiVersion.ApiKey).
+			WithField("min_version", apiVersion.MinVersion).
+			WithField("max_version", apiVersion.MaxVersion).
+			Debug("API version")
+	}
+	logger.WithField("check", "apiversions").WithField("apis", len(apiVersions)).Info("Fetched API versions")
+
+	// Clear the deadline so the connection can be re-used:
+	conn.SetDeadline(time.Time{})
+	return conn, true
+}
+
+// doctorReportCertificates logs the certificate chain presented by a broker:
+func (cli *CLI) doctorReportCertificates(logger *logrus.Entry, connectionState tls.ConnectionState) {
+	logger.
+		WithField("cipher_suite", tls.CipherSuiteName(connectionState.CipherSuite)).
+		WithField("version", tls.VersionName(connectionState.Version)).
+		Info("TLS handshake succeeded")
+
+	for depth, certificate := range connectionState.PeerCertificates {
+		certificateLogger := logger.
+			WithField("depth", depth).
+			WithField("subject", certificate.Subject.String()).
+			WithField("issuer", certificate.Issuer.String()).
+			WithField("not_after", certificate.NotAfter.Format(time.RFC3339))
+
+		switch remaining := time.Until(certificate.NotAfter); {
+		case remaining < 0:
+			certificateLogger.Error("Certificate has expired")
+		case remaining < certificateExpiryWarning:
+			certificateLogger.WithField("remaining", remaining.Round(time.Hour).String()).Warn("Certificate expires soon")
+		default:
+			certificateLogger.Info("Certificate")
+		}
+	}
+}
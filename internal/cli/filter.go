@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// messageFilter decides which records to print, using an expression (eg `key == "abc" && value.status == "FAILED"`):
+type messageFilter struct {
+	program *vm.Program
+}
+
+// filterEnvironment holds the variables available to filter expressions:
+type filterEnvironment struct {
+	Headers   map[string]string `expr:"headers"`
+	Key       string            `expr:"key"`
+	Offset    int64             `expr:"offset"`
+	Partition int               `expr:"partition"`
+	Timestamp time.Time         `expr:"timestamp"`
+	Topic     string            `expr:"topic"`
+	Value     interface{}       `expr:"value"`
+}
+
+// newFilterEnvironment prepares the variables for a record:
+func newFilterEnvironment(r *record) filterEnvironment {
+	environment := filterEnvironment{
+		Headers:   r.Headers,
+		Key:       r.Key,
+		Offset:    r.Offset,
+		Partition: r.Partition,
+		Timestamp: r.Timestamp,
+		Topic:     r.Topic,
+		Value:     r.Value,
+	}
+
+	// Values which are JSON can be navigated (eg value.items[0].sku), anything else is a plain string:
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(r.Value), &parsed); err == nil {
+		environment.Value = parsed
+	}
+
+	return environment
+}
+
+// newMessageFilter compiles a filter expression (returning nil if there isn't one):
+func newMessageFilter(expression string) (*messageFilter, error) {
+	if expression == "" {
+		return nil, nil
+	}
+
+	program, err := expr.Compile(expression, expr.Env(filterEnvironment{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	return &messageFilter{program: program}, nil
+}
+
+// match evaluates the filter against a record (a nil filter matches everything):
+func (mf *messageFilter) match(r *record) (bool, error) {
+	if mf == nil {
+		return true, nil
+	}
+
+	result, err := expr.Run(mf.program, newFilterEnvironment(r))
+	if err != nil {
+		return false, err
+	}
+
+	return result.(bool), nil
+}
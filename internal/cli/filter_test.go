@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageFilter(t *testing.T) {
+
+	// A decoded record:
+	testRecord := &record{
+		Headers:   map[string]string{"tenant": "x"},
+		Key:       "abc",
+		Offset:    1000,
+		Partition: 3,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Topic:     "orders",
+		Value:     `{"status":"FAILED","items":[{"sku":"s-1","quantity":2}]}`,
+	}
+
+	// No filter matches everything:
+	var noFilter *messageFilter
+	matched, err := noFilter.match(testRecord)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	// Expressions can use the key, JSON paths in the value, headers and coordinates:
+	for expression, expected := range map[string]bool{
+		`key == "abc" && value.status == "FAILED" && headers["tenant"] == "x"`: true,
+		`value.items[0].sku == "s-1" && value.items[0].quantity > 1`:           true,
+		`partition == 3 && offset >= 1000 && topic == "orders"`:                true,
+		`timestamp > date("2024-01-01T00:00:00Z")`:                             true,
+		`headers["tenant"] == "y"`:                                             false,
+		`value?.missing?.field == "z"`:                                         false,
+	} {
+		filter, err := newMessageFilter(expression)
+		assert.NoError(t, err, "Error while compiling %s", expression)
+		matched, err := filter.match(testRecord)
+		assert.NoError(t, err, "Error while evaluating %s", expression)
+		assert.Equal(t, expected, matched, expression)
+	}
+
+	// Plain-text values are strings:
+	filter, err := newMessageFilter(`value contains "needle"`)
+	assert.NoError(t, err)
+	matched, err = filter.match(&record{Value: "a needle in a haystack"})
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	// Expressions must be boolean:
+	_, err = newMessageFilter(`offset + 1`)
+	assert.Error(t, err, "Expected a non-boolean filter to be refused")
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/configuration"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+func (cli *CLI) initGet() {
+	getCommand := cli.getCommand()
+	getCommand.PersistentFlags().Duration("timeout", 30*time.Second, "How long to wait for the message")
+	addDeserializerFlags(getCommand)
+	addOutputFlags(getCommand)
+	cli.SetCommand("get", "root", getCommand)
+}
+
+// getCommand deals with fetching a single message:
+func (cli *CLI) getCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:        "get <topic> <partition> <offset>",
+		Short:      "Fetch exactly one message (by partition and offset)",
+		Args:       cobra.ExactArgs(3),
+		ArgAliases: []string{"topic", "partition", "offset"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the timeout flag:
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "timeout").Fatal("Unable to get flag")
+			}
+
+			// Get deserializers for the message keys, values and headers:
+			deserializers, err := cli.messageDeserializers(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare deserializers")
+			}
+
+			// Get a formatter for the output:
+			formatter, err := cli.recordFormatter(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare an output formatter")
+			}
+
+			// Get the topic name, partition and offset:
+			topicName := args[0]
+			partition, err := strconv.Atoi(args[1])
+			if err != nil {
+				cli.logger.WithError(err).WithField("partition", args[1]).Fatal("Invalid partition")
+			}
+			offset, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				cli.logger.WithError(err).WithField("offset", args[2]).Fatal("Invalid offset")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Getting message %s/%d/%d", topicName, partition, offset)
+
+			// Make sure the offset is between the watermarks (otherwise we'd wait for it forever):
+			earliestOffsets, err := cli.listOffsets(topicName, []int{partition}, kafka.FirstOffset)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the earliest offset")
+			}
+			latestOffsets, err := cli.listOffsets(topicName, []int{partition}, kafka.LastOffset)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to list the latest offset")
+			}
+			if !offsetInRange(offset, earliestOffsets[partition], latestOffsets[partition]) {
+				cli.logger.
+					WithField("earliest", earliestOffsets[partition]).
+					WithField("latest", latestOffsets[partition]).
+					WithField("offset", offset).
+					Fatal("Offset is out of range")
+			}
+
+			// Get a reader for the partition:
+			reader, err := cli.config.Kafka.PartitionReader(cli.logger, topicName, partition, offset, configuration.ReaderOptions{})
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).WithField("partition", partition).Fatal("Unable to prepare a consumer")
+			}
+			defer reader.Close()
+
+			// Fetch the message:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			message, err := reader.FetchMessage(ctx)
+			if err != nil {
+				cli.logger.WithError(err).WithField("offset", offset).WithField("partition", partition).Fatal("Unable to fetch the message")
+			}
+
+			// Compaction (or transaction markers) can leave gaps, in which case we get the next message:
+			if message.Offset != offset {
+				cli.logger.WithField("next", message.Offset).WithField("offset", offset).Fatal("There is no message at this offset (it may have been compacted)")
+			}
+
+			// Decode the message:
+			decoded, err := deserializers.record(message)
+			if decoded == nil {
+				cli.logger.WithError(err).WithField("offset", offset).WithField("partition", partition).Fatal("Unable to deserialize the message")
+			}
+			if err != nil {
+				cli.logger.WithError(err).WithField("offset", offset).WithField("partition", partition).Warn("Message failed schema validation")
+			}
+			decoded.TimestampType = cli.timestampType(message.Topic)
+
+			// Print the message:
+			output, err := formatter.format(decoded)
+			if err != nil {
+				cli.logger.WithError(err).WithField("offset", offset).WithField("partition", partition).Fatal("Unable to format the message")
+			}
+			fmt.Println(output)
+		},
+	}
+}
+
+// offsetInRange reports whether there can be a message at an offset (the latest offset is where the next message will go, so it's excluded):
+func offsetInRange(offset, earliest, latest int64) bool {
+	return offset >= earliest && offset < latest
+}
@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetInRange(t *testing.T) {
+	// Between the earliest offset and the high watermark (which is where the next message will go):
+	assert.True(t, offsetInRange(100, 100, 250))
+	assert.True(t, offsetInRange(249, 100, 250))
+	assert.False(t, offsetInRange(99, 100, 250))
+	assert.False(t, offsetInRange(250, 100, 250))
+
+	// An empty partition has nothing to get:
+	assert.False(t, offsetInRange(0, 0, 0))
+}
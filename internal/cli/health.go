@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// healthReport lists the problems found in a cluster:
+type healthReport struct {
+	ControllerMissing bool
+	Leaderless        []partitionState
+	MissingBrokers    []int
+	OfflineReplicas   []partitionState
+	UnderMinISR       []partitionState
+	UnderReplicated   []partitionState
+}
+
+// healthThresholds are how many of each problem we tolerate:
+type healthThresholds struct {
+	Leaderless      int
+	MissingBrokers  int
+	OfflineReplicas int
+	UnderMinISR     int
+	UnderReplicated int
+}
+
+// checkHealth looks for problems in the cluster state. Brokers count as missing when they're expected (or hold replicas) but aren't in the metadata:
+func checkHealth(state *clusterState, minISR map[string]int, expectedBrokers []int) *healthReport {
+	report := &healthReport{
+		ControllerMissing: !slices.Contains(state.Brokers, state.Controller),
+	}
+
+	missing := make(map[int]bool)
+	for _, broker := range expectedBrokers {
+		if !slices.Contains(state.Brokers, broker) {
+			missing[broker] = true
+		}
+	}
+
+	for _, partition := range state.Partitions {
+		offline := len(partition.Offline) > 0
+		for _, replica := range partition.Replicas {
+			if !slices.Contains(state.Brokers, replica) {
+				missing[replica] = true
+				offline = true
+			}
+		}
+
+		if partition.Leader < 0 {
+			report.Leaderless = append(report.Leaderless, partition)
+		}
+		if offline {
+			report.OfflineReplicas = append(report.OfflineReplicas, partition)
+		}
+		if len(partition.Isr) < len(partition.Replicas) {
+			report.UnderReplicated = append(report.UnderReplicated, partition)
+		}
+		if len(partition.Isr) < minISR[partition.Topic] {
+			report.UnderMinISR = append(report.UnderMinISR, partition)
+		}
+	}
+
+	for broker := range missing {
+		report.MissingBrokers = append(report.MissingBrokers, broker)
+	}
+	sort.Ints(report.MissingBrokers)
+
+	return report
+}
+
+// breaches returns a description of each threshold the report goes over:
+func (hr *healthReport) breaches(thresholds healthThresholds) []string {
+	var breaches []string
+	check := func(problem string, found, threshold int) {
+		if found > threshold {
+			breaches = append(breaches, fmt.Sprintf("%d %s (more than %d)", found, problem, threshold))
+		}
+	}
+
+	if hr.ControllerMissing {
+		breaches = append(breaches, "no controller")
+	}
+	check("leaderless partitions", len(hr.Leaderless), thresholds.Leaderless)
+	check("missing brokers", len(hr.MissingBrokers), thresholds.MissingBrokers)
+	check("partitions with offline replicas", len(hr.OfflineReplicas), thresholds.OfflineReplicas)
+	check("under-min-ISR partitions", len(hr.UnderMinISR), thresholds.UnderMinISR)
+	check("under-replicated partitions", len(hr.UnderReplicated), thresholds.UnderReplicated)
+
+	return breaches
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHealth(t *testing.T) {
+	state := &clusterState{
+		Brokers:    []int{1, 2, 3},
+		Controller: 1,
+		Partitions: []partitionState{
+			{Isr: []int{1, 2, 3}, Leader: 1, Partition: 0, Replicas: []int{1, 2, 3}, Topic: "healthy"},
+			{Isr: []int{1, 2}, Leader: 1, Partition: 0, Replicas: []int{1, 2, 4}, Topic: "orders"},
+			{Isr: []int{2}, Leader: 2, Partition: 1, Offline: []int{1}, Replicas: []int{1, 2}, Topic: "orders"},
+			{Leader: -1, Partition: 2, Offline: []int{5}, Replicas: []int{5}, Topic: "orders"},
+		},
+	}
+
+	// Brokers 4 and 5 hold replicas but aren't in the metadata, and 6 is expected:
+	report := checkHealth(state, map[string]int{"orders": 2}, []int{1, 2, 3, 6})
+
+	assert.False(t, report.ControllerMissing)
+	assert.Equal(t, []int{4, 5, 6}, report.MissingBrokers)
+	assert.Len(t, report.Leaderless, 1)
+	assert.Len(t, report.OfflineReplicas, 3)
+	assert.Len(t, report.UnderReplicated, 3)
+	assert.Len(t, report.UnderMinISR, 2)
+
+	// Only the problems above their thresholds are breaches:
+	breaches := report.breaches(healthThresholds{MissingBrokers: 3, OfflineReplicas: 3, UnderMinISR: 1, UnderReplicated: 3})
+	assert.Equal(t, []string{"1 leaderless partitions (more than 0)", "2 under-min-ISR partitions (more than 1)"}, breaches)
+}
+
+func TestCheckHealthControllerMissing(t *testing.T) {
+	report := checkHealth(&clusterState{Brokers: []int{1, 2}, Controller: -1}, nil, nil)
+	assert.True(t, report.ControllerMissing)
+	assert.Equal(t, []string{"no controller"}, report.breaches(healthThresholds{}))
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/chrusty/kafka-cli/internal/protocol/describelogdirs"
+	"github.com/segmentio/kafka-go"
+)
+
+// Replica sizes closer than this never count as diverging (so small partitions don't get flagged):
+const divergenceMinBytes = 1 << 20
+
+// Ways to sort sizes:
+const (
+	sortByName = "name"
+	sortBySize = "size"
+)
+
+// logDir is one log dir of a broker (with an error if it's offline, or the broker couldn't describe it):
+type logDir struct {
+	Broker int
+	Error  error
+	Path   string
+}
+
+// replicaSize is the size of one replica of a partition (Future is set on the copy a replica is being moved to between log dirs):
+type replicaSize struct {
+	Broker    int
+	Bytes     int64
+	Future    bool
+	LogDir    string
+	OffsetLag int64
+	Partition int
+	Topic     string
+}
+
+// sizeKey is what sizes are totalled by (fields which aren't being grouped by are left empty):
+type sizeKey struct {
+	Broker    int
+	LogDir    string
+	Partition int
+	Topic     string
+}
+
+// sizeTotal is the total size of some replicas:
+type sizeTotal struct {
+	Bytes    int64
+	Replicas int
+}
+
+// sizeDivergence is a partition whose replicas are different sizes:
+type sizeDivergence struct {
+	Partition     int
+	Sizes         map[int]int64
+	SpreadPercent float64
+	Topic         string
+}
+
+// describeLogDirs asks every broker for its log dirs and the replicas in them (of some topics, or every topic).
+// Brokers which can't be reached are reported as a log dir with an error:
+func (cli *CLI) describeLogDirs(topicNames ...string) ([]logDir, []replicaSize, error) {
+	state, err := cli.clusterState(topicNames...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A nil topic list asks for every partition:
+	var requestTopics []describelogdirs.RequestTopic
+	for _, partition := range state.Partitions {
+		if len(topicNames) == 0 {
+			break
+		}
+		if len(requestTopics) == 0 || requestTopics[len(requestTopics)-1].Topic != partition.Topic {
+			requestTopics = append(requestTopics, describelogdirs.RequestTopic{Topic: partition.Topic})
+		}
+		last := &requestTopics[len(requestTopics)-1]
+		last.Partitions = append(last.Partitions, int32(partition.Partition))
+	}
+
+	var logDirs []logDir
+	var replicas []replicaSize
+	for _, broker := range state.Brokers {
+		response, err := cli.adminClient.Transport.RoundTrip(context.TODO(), cli.adminClient.Addr, describelogdirs.NewRequest(int32(broker), requestTopics))
+		if err != nil {
+			logDirs = append(logDirs, logDir{Broker: broker, Error: err})
+			continue
+		}
+
+		for _, result := range response.(*describelogdirs.Response).Results {
+			dir := logDir{Broker: broker, Path: result.LogDir}
+			if result.ErrorCode != 0 {
+				dir.Error = kafka.Error(result.ErrorCode)
+			}
+			logDirs = append(logDirs, dir)
+
+			for _, topic := range result.Topics {
+				for _, partition := range topic.Partitions {
+					replicas = append(replicas, replicaSize{
+						Broker:    broker,
+						Bytes:     partition.PartitionSize,
+						Future:    partition.IsFutureKey,
+						LogDir:    result.LogDir,
+						OffsetLag: partition.OffsetLag,
+						Partition: int(partition.PartitionIndex),
+						Topic:     topic.Name,
+					})
+				}
+			}
+		}
+	}
+
+	return logDirs, replicas, nil
+}
+
+// currentReplicas leaves out future replicas (so a partition being moved between log dirs isn't counted twice):
+func currentReplicas(replicas []replicaSize) []replicaSize {
+	var current []replicaSize
+	for _, replica := range replicas {
+		if !replica.Future {
+			current = append(current, replica)
+		}
+	}
+	return current
+}
+
+// totalSizes adds up the sizes of replicas, grouped by a key (it counts whatever it's given, so callers leave out future replicas if they don't want them):
+func totalSizes(replicas []replicaSize, key func(replicaSize) sizeKey) map[sizeKey]*sizeTotal {
+	totals := make(map[sizeKey]*sizeTotal)
+	for _, replica := range replicas {
+		total, ok := totals[key(replica)]
+		if !ok {
+			total = &sizeTotal{}
+			totals[key(replica)] = total
+		}
+		total.Bytes += replica.Bytes
+		total.Replicas++
+	}
+	return totals
+}
+
+// sortedSizes returns the keys of some totals, largest first (or by name):
+func sortedSizes(totals map[sizeKey]*sizeTotal, sortBy string) []sizeKey {
+	keys := make([]sizeKey, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b sizeKey) int {
+		if sortBy == sortBySize && totals[a].Bytes != totals[b].Bytes {
+			return cmp.Compare(totals[b].Bytes, totals[a].Bytes)
+		}
+		return compareSizeKeys(a, b)
+	})
+	return keys
+}
+
+// compareSizeKeys orders keys by topic, partition, broker then log dir:
+func compareSizeKeys(a, b sizeKey) int {
+	if a.Topic != b.Topic {
+		return cmp.Compare(a.Topic, b.Topic)
+	}
+	if a.Partition != b.Partition {
+		return cmp.Compare(a.Partition, b.Partition)
+	}
+	if a.Broker != b.Broker {
+		return cmp.Compare(a.Broker, b.Broker)
+	}
+	return cmp.Compare(a.LogDir, b.LogDir)
+}
+
+// divergentReplicas finds partitions whose largest replica is more than some percentage bigger than their smallest (ignoring future replicas, which are still being copied):
+func divergentReplicas(replicas []replicaSize, maxPercent float64) []sizeDivergence {
+	sizes := make(map[sizeKey]map[int]int64)
+	for _, replica := range replicas {
+		if replica.Future {
+			continue
+		}
+		key := sizeKey{Partition: replica.Partition, Topic: replica.Topic}
+		if sizes[key] == nil {
+			sizes[key] = make(map[int]int64)
+		}
+		sizes[key][replica.Broker] = replica.Bytes
+	}
+
+	var divergences []sizeDivergence
+	for key, brokerSizes := range sizes {
+		var smallest, largest int64 = -1, 0
+		for _, bytes := range brokerSizes {
+			if smallest < 0 || bytes < smallest {
+				smallest = bytes
+			}
+			largest = max(largest, bytes)
+		}
+		if len(brokerSizes) < 2 || largest-smallest < divergenceMinBytes {
+			continue
+		}
+		// How much bigger the largest replica is than the smallest (an empty replica is infinitely smaller):
+		spread := math.Inf(1)
+		if smallest > 0 {
+			spread = float64(largest-smallest) * 100 / float64(smallest)
+		}
+		if spread > maxPercent {
+			divergences = append(divergences, sizeDivergence{Partition: key.Partition, Sizes: brokerSizes, SpreadPercent: spread, Topic: key.Topic})
+		}
+	}
+
+	slices.SortFunc(divergences, func(a, b sizeDivergence) int {
+		return compareSizeKeys(sizeKey{Partition: a.Partition, Topic: a.Topic}, sizeKey{Partition: b.Partition, Topic: b.Topic})
+	})
+	return divergences
+}
+
+// humanBytes formats a number of bytes in binary units (eg 1.5 GiB):
+func humanBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	divisor, exponent := int64(unit), 0
+	for remaining := bytes / unit; remaining >= unit; remaining /= unit {
+		divisor *= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(divisor), "KMGTPE"[exponent])
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testReplicaSizes = []replicaSize{
+	{Broker: 1, Bytes: 10 << 20, LogDir: "/data/a", Partition: 0, Topic: "orders"},
+	{Broker: 2, Bytes: 10 << 20, LogDir: "/data/a", Partition: 0, Topic: "orders"},
+	{Broker: 1, Bytes: 40 << 20, LogDir: "/data/b", Partition: 1, Topic: "orders"},
+	{Broker: 2, Bytes: 20 << 20, LogDir: "/data/a", Partition: 1, Topic: "orders"},
+	{Broker: 2, Bytes: 1 << 20, Future: true, LogDir: "/data/b", Partition: 1, Topic: "orders"},
+	{Broker: 1, Bytes: 1000, LogDir: "/data/a", Partition: 0, Topic: "payments"},
+	{Broker: 2, Bytes: 10, LogDir: "/data/a", Partition: 0, Topic: "payments"},
+}
+
+func TestTotalSizes(t *testing.T) {
+	byTopic := totalSizes(currentReplicas(testReplicaSizes), func(replica replicaSize) sizeKey { return sizeKey{Topic: replica.Topic} })
+	assert.Equal(t, &sizeTotal{Bytes: 80 << 20, Replicas: 4}, byTopic[sizeKey{Topic: "orders"}])
+	assert.Equal(t, &sizeTotal{Bytes: 1010, Replicas: 2}, byTopic[sizeKey{Topic: "payments"}])
+
+	// Future replicas still count towards the log dir they're being copied to:
+	byLogDir := totalSizes(testReplicaSizes, func(replica replicaSize) sizeKey { return sizeKey{Broker: replica.Broker, LogDir: replica.LogDir} })
+	assert.Len(t, byLogDir, 4)
+	assert.Equal(t, &sizeTotal{Bytes: 1 << 20, Replicas: 1}, byLogDir[sizeKey{Broker: 2, LogDir: "/data/b"}])
+
+	// Largest first, or by name (with partitions in numeric order):
+	assert.Equal(t, []sizeKey{{Broker: 1, LogDir: "/data/b"}, {Broker: 2, LogDir: "/data/a"}, {Broker: 1, LogDir: "/data/a"}, {Broker: 2, LogDir: "/data/b"}}, sortedSizes(byLogDir, sortBySize))
+	byPartition := totalSizes(append(testReplicaSizes, replicaSize{Bytes: 1, Partition: 10, Topic: "orders"}), func(replica replicaSize) sizeKey {
+		return sizeKey{Partition: replica.Partition, Topic: replica.Topic}
+	})
+	assert.Equal(t, []sizeKey{{Topic: "orders"}, {Topic: "orders", Partition: 1}, {Topic: "orders", Partition: 10}, {Topic: "payments"}}, sortedSizes(byPartition, sortByName))
+}
+
+func TestDivergentReplicas(t *testing.T) {
+	// Only orders/1 diverges (future replicas are ignored, and payments/0 is too small to matter):
+	divergences := divergentReplicas(testReplicaSizes, 20)
+	assert.Len(t, divergences, 1)
+	assert.Equal(t, "orders", divergences[0].Topic)
+	assert.Equal(t, 1, divergences[0].Partition)
+	assert.Equal(t, map[int]int64{1: 40 << 20, 2: 20 << 20}, divergences[0].Sizes)
+	assert.Equal(t, 100.0, divergences[0].SpreadPercent)
+
+	// Nothing diverges more than 150%:
+	assert.Empty(t, divergentReplicas(testReplicaSizes, 150))
+
+	// An empty replica always diverges:
+	divergences = divergentReplicas(append(testReplicaSizes, replicaSize{Broker: 3, Bytes: 0, LogDir: "/data/a", Partition: 0, Topic: "orders"}), 150)
+	assert.Len(t, divergences, 1)
+	assert.Equal(t, 0, divergences[0].Partition)
+	assert.True(t, math.IsInf(divergences[0].SpreadPercent, 1))
+}
+
+func TestHumanBytes(t *testing.T) {
+	assert.Equal(t, "0 B", humanBytes(0))
+	assert.Equal(t, "1023 B", humanBytes(1023))
+	assert.Equal(t, "1.0 KiB", humanBytes(1024))
+	assert.Equal(t, "1.5 MiB", humanBytes(3<<19))
+	assert.Equal(t, "2.0 TiB", humanBytes(2<<40))
+}
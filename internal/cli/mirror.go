@@ -0,0 +1,447 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/configuration"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// How long mirror waits for more messages before publishing a partial batch:
+const mirrorFlushInterval = 100 * time.Millisecond
+
+// rateLimiter spaces events out evenly (a nil rateLimiter doesn't limit anything):
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a rateLimiter for a number of events per second (or nil for no limit):
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next event is allowed:
+func (rl *rateLimiter) wait(ctx context.Context) error {
+	if rl == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	delay := rl.next.Sub(now)
+	rl.next = rl.next.Add(rl.interval)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// offsetMapping records where a source message ended up:
+type offsetMapping struct {
+	SourceOffset    int64     `json:"source_offset"`
+	SourcePartition int       `json:"source_partition"`
+	SourceTopic     string    `json:"source_topic"`
+	TargetOffset    int64     `json:"target_offset"`
+	TargetPartition int       `json:"target_partition"`
+	TargetTopic     string    `json:"target_topic"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// offsetLog writes offset mappings to a file (one JSON object per line):
+type offsetLog struct {
+	encoder *json.Encoder
+	file    *os.File
+	mutex   sync.Mutex
+}
+
+// newOffsetLog appends to an offset log file (or returns nil if there isn't one):
+func newOffsetLog(path string) (*offsetLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &offsetLog{encoder: json.NewEncoder(file), file: file}, nil
+}
+
+// record writes a mapping for each written message (the source message travels in WriterData):
+func (ol *offsetLog) record(messages []kafka.Message) error {
+	if ol == nil {
+		return nil
+	}
+
+	ol.mutex.Lock()
+	defer ol.mutex.Unlock()
+
+	for _, message := range messages {
+		source, ok := message.WriterData.(kafka.Message)
+		if !ok {
+			continue
+		}
+		err := ol.encoder.Encode(offsetMapping{
+			SourceOffset:    source.Offset,
+			SourcePartition: source.Partition,
+			SourceTopic:     source.Topic,
+			TargetOffset:    message.Offset,
+			TargetPartition: message.Partition,
+			TargetTopic:     message.Topic,
+			Timestamp:       message.Time,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the offset log file:
+func (ol *offsetLog) Close() error {
+	if ol == nil {
+		return nil
+	}
+	return ol.file.Close()
+}
+
+// mirrorMessage copies a message for the target topic (keeping its key, headers and timestamp, and optionally its partition):
+func mirrorMessage(source kafka.Message, preservePartition bool) kafka.Message {
+	message := kafka.Message{
+		Headers:    append([]kafka.Header(nil), source.Headers...),
+		Key:        source.Key,
+		Time:       source.Time,
+		Value:      source.Value,
+		WriterData: source,
+	}
+	if preservePartition {
+		message.Partition = source.Partition
+	}
+	return message
+}
+
+func (cli *CLI) initMirror() {
+	mirrorCommand := cli.mirrorCommand()
+	mirrorCommand.PersistentFlags().Int("batch-size", 500, "How many messages to publish at once")
+	mirrorCommand.PersistentFlags().String("checkpoint-file", "", "Without a group, resume from (and save) the position of each source partition in this file")
+	mirrorCommand.PersistentFlags().Int("count", 0, "Stop after mirroring this many messages (0 for no limit)")
+	mirrorCommand.PersistentFlags().String("filter", "", `Only mirror messages matching an expression (eg 'headers["tenant"] == "x"')`)
+	mirrorCommand.PersistentFlags().String("groupid", "", "Consumer group ID for the source (so a later run resumes where this one stopped)")
+	mirrorCommand.PersistentFlags().String("offset-log", "", "Append the source and target offset of every mirrored message to this file")
+	mirrorCommand.PersistentFlags().Bool("preserve-partition", false, "Publish each message to the same partition number it came from (instead of partitioning by key)")
+	mirrorCommand.PersistentFlags().Float64("rate", 0, "The most messages to mirror per second (0 for no limit)")
+	mirrorCommand.PersistentFlags().String("rename", "", "The topic to mirror to (defaults to the source topic name)")
+	mirrorCommand.PersistentFlags().Int("replication-factor", 0, "The replication factor to create the target topic with (defaults to the source's)")
+	mirrorCommand.PersistentFlags().String("source-context", "", "The context to read from (eg prod reads PROD_KAFKA_* env-vars, defaults to KAFKA_*)")
+	mirrorCommand.PersistentFlags().String("target-context", "", "The context to write to (eg staging reads STAGING_KAFKA_* env-vars, defaults to KAFKA_*)")
+	mirrorCommand.PersistentFlags().String("topic", "", "The topic to mirror (required)")
+	mirrorCommand.MarkPersistentFlagRequired("topic")
+	addDeserializerFlags(mirrorCommand)
+	cli.SetCommand("mirror", "root", mirrorCommand)
+}
+
+// mirrorCommand deals with copying messages between topics (or clusters):
+func (cli *CLI) mirrorCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "mirror",
+		Short: "Stream messages from a topic to another topic (or cluster), keeping their keys, headers and timestamps",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the context flags:
+			sourceContext, err := cmd.Flags().GetString("source-context")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "source-context").Fatal("Unable to get flag")
+			}
+			targetContext, err := cmd.Flags().GetString("target-context")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "target-context").Fatal("Unable to get flag")
+			}
+
+			// Get the topic flags:
+			topicName, err := cmd.Flags().GetString("topic")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topic").Fatal("Unable to get flag")
+			}
+			targetTopicName, err := cmd.Flags().GetString("rename")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "rename").Fatal("Unable to get flag")
+			}
+			if targetTopicName == "" {
+				targetTopicName = topicName
+			}
+			if sourceContext == targetContext && targetTopicName == topicName {
+				cli.logger.Fatal("Mirroring a topic onto itself (use --rename or a different --target-context)")
+			}
+			replicationFactor, err := cmd.Flags().GetInt("replication-factor")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "replication-factor").Fatal("Unable to get flag")
+			}
+
+			// Get the batching and rate flags:
+			batchSize, err := cmd.Flags().GetInt("batch-size")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "batch-size").Fatal("Unable to get flag")
+			}
+			if batchSize < 1 {
+				batchSize = 1
+			}
+			rate, err := cmd.Flags().GetFloat64("rate")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "rate").Fatal("Unable to get flag")
+			}
+			limiter := newRateLimiter(rate)
+			count, err := cmd.Flags().GetInt("count")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "count").Fatal("Unable to get flag")
+			}
+			preservePartition, err := cmd.Flags().GetBool("preserve-partition")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "preserve-partition").Fatal("Unable to get flag")
+			}
+
+			// Get the progress flags:
+			groupId, err := cmd.Flags().GetString("groupid")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "groupid").Fatal("Unable to get flag")
+			}
+			checkpointFile, err := cmd.Flags().GetString("checkpoint-file")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "checkpoint-file").Fatal("Unable to get flag")
+			}
+			var mirrorCheckpoint *checkpoint
+			if checkpointFile != "" {
+				if groupId != "" {
+					cli.logger.Fatal("--checkpoint-file can't be used with a consumer group")
+				}
+				if mirrorCheckpoint, err = loadCheckpoint(checkpointFile); err != nil {
+					cli.logger.WithError(err).WithField("file", checkpointFile).Fatal("Unable to load the checkpoint file")
+				}
+			}
+			offsetLogFile, err := cmd.Flags().GetString("offset-log")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "offset-log").Fatal("Unable to get flag")
+			}
+			mappings, err := newOffsetLog(offsetLogFile)
+			if err != nil {
+				cli.logger.WithError(err).WithField("file", offsetLogFile).Fatal("Unable to open the offset log")
+			}
+			defer mappings.Close()
+
+			// Get the filter flag:
+			filterExpression, err := cmd.Flags().GetString("filter")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "filter").Fatal("Unable to get flag")
+			}
+			filter, err := newMessageFilter(filterExpression)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a filter")
+			}
+
+			// Get deserializers for the message keys, values and headers (which the filter sees):
+			deserializers, err := cli.messageDeserializers(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare deserializers")
+			}
+
+			// Connect to both clusters:
+			source, err := cli.forContext(sourceContext)
+			if err != nil {
+				cli.logger.WithError(err).WithField("context", sourceContext).Fatal("Unable to load the source context")
+			}
+			target, err := cli.forContext(targetContext)
+			if err != nil {
+				cli.logger.WithError(err).WithField("context", targetContext).Fatal("Unable to load the target context")
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", source.config.Kafka.SaslMechanism).
+				WithField("security", source.config.Kafka.SecurityProtocol).
+				WithField("servers", source.config.Kafka.BootstrapServers).
+				WithField("target_servers", target.config.Kafka.BootstrapServers).
+				WithField("username", source.config.Kafka.Username).
+				Debugf("Mirroring topic %s to %s", topicName, targetTopicName)
+
+			// Create the target topic if it doesn't exist (like the source, with the same config overrides):
+			sourceTopic, err := source.topicMetadata(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to describe the source topic")
+			}
+			configs, err := source.topicConfigOverrides(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to retrieve the source topic config")
+			}
+			if replicationFactor < 1 && len(sourceTopic.Partitions) > 0 {
+				replicationFactor = len(sourceTopic.Partitions[0].Replicas)
+			}
+			if _, err := target.createTopic(targetTopicName, len(sourceTopic.Partitions), replicationFactor, configs); err != nil {
+				cli.logger.WithError(err).WithField("topic", targetTopicName).Fatal("Unable to create the target topic")
+			}
+			targetPartitions, err := target.topicPartitions(targetTopicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", targetTopicName).Fatal("Unable to describe the target topic")
+			}
+			if preservePartition && len(targetPartitions) < len(sourceTopic.Partitions) {
+				cli.logger.
+					WithField("source_partitions", len(sourceTopic.Partitions)).
+					WithField("target_partitions", len(targetPartitions)).
+					Fatal("The target topic has fewer partitions than the source (so partitions can't be preserved)")
+			}
+
+			// Get a producer for the target (which logs where each message ended up):
+			producer, err := target.config.Kafka.Producer(cli.logger, targetTopicName)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a Kafka producer")
+			}
+			producer.Balancer = kafka.Murmur2Balancer{}
+			if preservePartition {
+				producer.Balancer = partitionBalancer{}
+			}
+			producer.BatchSize = batchSize
+			producer.BatchTimeout = 10 * time.Millisecond
+			producer.Completion = func(messages []kafka.Message, err error) {
+				if err != nil {
+					return
+				}
+				if err := mappings.record(messages); err != nil {
+					cli.logger.WithError(err).Error("Unable to write to the offset log")
+				}
+			}
+			defer producer.Close()
+
+			// Read the source topic until we're interrupted:
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			consumer, err := source.subscribe(ctx, subscriptionOptions{
+				checkpoint:      mirrorCheckpoint,
+				groupId:         groupId,
+				offset:          kafka.FirstOffset,
+				partition:       -1,
+				readerOptions:   configuration.ReaderOptions{IsolationLevel: kafka.ReadCommitted},
+				refreshInterval: time.Minute,
+				topicNames:      []string{topicName},
+			})
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).WithField("group", groupId).Fatal("Unable to prepare a consumer")
+			}
+			defer consumer.Close()
+			committer := newCommitter(commitAfterWrite, 5*time.Second, consumer, mirrorCheckpoint)
+
+			// Periodically report our progress:
+			startTime := time.Now()
+			var totalErrors, messagesConsumed, messagesMirrored int64
+			go func() {
+				for {
+					time.Sleep(time.Second)
+					cli.logger.WithField("errors", atomic.LoadInt64(&totalErrors)).WithField("mirrored", atomic.LoadInt64(&messagesMirrored)).WithField("scanned", atomic.LoadInt64(&messagesConsumed)).WithField("messages/s", atomic.LoadInt64(&messagesMirrored)/int64(time.Since(startTime).Seconds()+1)).Info("Progress report")
+				}
+			}()
+
+			// Publish in batches, only recording our progress once they're written:
+			var batch, handled []kafka.Message
+			publish := func(ctx context.Context) {
+				if len(batch) > 0 {
+					if err := producer.WriteMessages(ctx, batch...); err != nil {
+						cli.logger.WithError(err).WithField("messages", len(batch)).Fatal("Unable to publish to the target topic")
+					}
+					atomic.AddInt64(&messagesMirrored, int64(len(batch)))
+				}
+				if err := committer.handled(ctx, handled...); err != nil {
+					cli.logger.WithError(err).Error("Unable to commit")
+					atomic.AddInt64(&totalErrors, 1)
+				}
+				batch, handled = batch[:0], handled[:0]
+			}
+
+			for count <= 0 || int(messagesMirrored)+len(batch) < count {
+
+				// Get a message (publishing what we have if nothing arrives for a while):
+				readCtx, readCancel := context.WithTimeout(ctx, mirrorFlushInterval)
+				consumed, err := consumer.ReadMessage(readCtx)
+				readCancel()
+				if ctx.Err() != nil {
+					break
+				}
+				if errors.Is(err, context.DeadlineExceeded) {
+					publish(ctx)
+					continue
+				}
+				if err != nil {
+					cli.logger.WithError(err).Error("Unable to consume a message")
+					atomic.AddInt64(&totalErrors, 1)
+					continue
+				}
+				message := consumed.message
+				atomic.AddInt64(&messagesConsumed, 1)
+				handled = append(handled, message)
+
+				// Skip messages which don't match our filter:
+				if filter != nil {
+					decoded, err := deserializers.record(message)
+					if decoded == nil {
+						cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Debug("Unable to deserialize a message")
+						decoded = deserializers.rawRecord(message)
+					}
+					decoded.TimestampType = source.timestampType(message.Topic)
+					matched, err := filter.match(decoded)
+					if err != nil {
+						cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Debug("Unable to evaluate the filter")
+					}
+					if !matched {
+						continue
+					}
+				}
+
+				// Mirror the message (as fast as we're allowed to):
+				if err := limiter.wait(ctx); err != nil {
+					break
+				}
+				batch = append(batch, mirrorMessage(message, preservePartition))
+				if len(batch) >= batchSize {
+					publish(ctx)
+				}
+			}
+
+			// Publish whatever is left, and commit (or save the checkpoint) on the way out:
+			commitCtx, commitCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer commitCancel()
+			publish(commitCtx)
+			if err := committer.close(commitCtx); err != nil {
+				cli.logger.WithError(err).Error("Unable to commit on exit")
+			}
+
+			cli.logger.
+				WithField("errors", totalErrors).
+				WithField("mirrored", messagesMirrored).
+				WithField("scanned", messagesConsumed).
+				WithField("target", targetTopicName).
+				WithField("topic", topicName).
+				Info("Mirror stopped")
+		},
+	}
+}
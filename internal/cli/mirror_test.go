@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorMessage(t *testing.T) {
+	source := kafka.Message{
+		Headers:   []kafka.Header{{Key: "tenant", Value: []byte("x")}},
+		Key:       []byte("order-1"),
+		Offset:    1234,
+		Partition: 5,
+		Time:      time.UnixMilli(1700000000123),
+		Topic:     "orders",
+		Value:     []byte(`{"status":"NEW"}`),
+	}
+
+	// Keys, headers, values and timestamps are kept (but not the topic or offset):
+	mirrored := mirrorMessage(source, false)
+	assert.Equal(t, source.Key, mirrored.Key)
+	assert.Equal(t, source.Headers, mirrored.Headers)
+	assert.Equal(t, source.Value, mirrored.Value)
+	assert.Equal(t, source.Time, mirrored.Time)
+	assert.Empty(t, mirrored.Topic)
+	assert.Zero(t, mirrored.Offset)
+	assert.Zero(t, mirrored.Partition)
+
+	// The partition is optional:
+	assert.Equal(t, 5, mirrorMessage(source, true).Partition)
+
+	// Once written, the offset log maps the source to the target:
+	path := filepath.Join(t.TempDir(), "offsets.jsonl")
+	mappings, err := newOffsetLog(path)
+	assert.NoError(t, err, "Error while opening an offset log")
+	mirrored.Topic, mirrored.Partition, mirrored.Offset = "orders-copy", 2, 17
+	assert.NoError(t, mappings.record([]kafka.Message{mirrored}))
+	assert.NoError(t, mappings.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	assert.True(t, scanner.Scan())
+	mapping := offsetMapping{}
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &mapping))
+	assert.Equal(t, offsetMapping{
+		SourceOffset:    1234,
+		SourcePartition: 5,
+		SourceTopic:     "orders",
+		TargetOffset:    17,
+		TargetPartition: 2,
+		TargetTopic:     "orders-copy",
+		Timestamp:       mapping.Timestamp,
+	}, mapping)
+	assert.True(t, source.Time.Equal(mapping.Timestamp))
+}
+
+func TestRateLimiter(t *testing.T) {
+
+	// No rate means no limit:
+	assert.Nil(t, newRateLimiter(0))
+	assert.NoError(t, (*rateLimiter)(nil).wait(context.Background()))
+
+	// Events are spaced out by the interval:
+	limiter := newRateLimiter(50)
+	assert.Equal(t, 20*time.Millisecond, limiter.interval)
+	startTime := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(startTime), 40*time.Millisecond)
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/configuration"
+	"github.com/segmentio/kafka-go"
+)
+
+// How long readRange waits for a message (by default) before giving up on the rest of a range (which may only be transaction markers):
+const readRangeIdleTimeout = 10 * time.Second
+
+// offsetRange is the span of offsets to read from one partition (end is exclusive):
+type offsetRange struct {
+	end       int64
+	partition int
+	start     int64
+}
+
+// stoppedShort reports whether reading got up to an offset before the end of the range:
+func (or offsetRange) stoppedShort(reached int64) bool {
+	return reached < or.end
+}
+
+// topicMetadata returns the metadata for a topic (its partitions, and where their replicas are):
+func (cli *CLI) topicMetadata(topicName string) (*kafka.Topic, error) {
+	kafkaMetadata, err := cli.adminClient.Metadata(context.TODO(), &kafka.MetadataRequest{
+		Topics: []string{topicName},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, topic := range kafkaMetadata.Topics {
+		if topic.Name != topicName {
+			continue
+		}
+		if topic.Error != nil {
+			return nil, topic.Error
+		}
+		return &topic, nil
+	}
+
+	return nil, fmt.Errorf("topic %s not found", topicName)
+}
+
+// topicPartitions returns the (sorted) partition IDs of a topic:
+func (cli *CLI) topicPartitions(topicName string) ([]int, error) {
+	topic, err := cli.topicMetadata(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]int, len(topic.Partitions))
+	for i, partition := range topic.Partitions {
+		partitions[i] = partition.ID
+	}
+	sort.Ints(partitions)
+	return partitions, nil
+}
+
+// listOffsets looks up one offset per partition, for either kafka.FirstOffset, kafka.LastOffset or a timestamp (in ms):
+func (cli *CLI) listOffsets(topicName string, partitions []int, timestamp int64) (map[int]int64, error) {
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = kafka.OffsetRequest{Partition: partition, Timestamp: timestamp}
+	}
+
+	response, err := cli.adminClient.ListOffsets(context.TODO(), &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topicName: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, partitionOffsets := range response.Topics[topicName] {
+		if partitionOffsets.Error != nil {
+			return nil, fmt.Errorf("unable to list offsets for partition %d: %w", partitionOffsets.Partition, partitionOffsets.Error)
+		}
+
+		offsets[partitionOffsets.Partition] = listedOffset(partitionOffsets, timestamp)
+	}
+
+	return offsets, nil
+}
+
+// listedOffset picks the offset we asked for out of a partition's ListOffsets response:
+func listedOffset(partitionOffsets kafka.PartitionOffsets, timestamp int64) int64 {
+	switch timestamp {
+	case kafka.FirstOffset:
+		return partitionOffsets.FirstOffset
+	case kafka.LastOffset:
+		return partitionOffsets.LastOffset
+	}
+
+	// Lookups by time come back keyed by offset (which is -1, or missing, if nothing is that recent):
+	offset := int64(-1)
+	for candidate := range partitionOffsets.Offsets {
+		if candidate >= 0 && (offset < 0 || candidate < offset) {
+			offset = candidate
+		}
+	}
+	return offset
+}
+
+// offsetsForTime looks up the first offset at or after a time for each partition (or -1 if there isn't one):
+func (cli *CLI) offsetsForTime(topicName string, partitions []int, at time.Time) (map[int]int64, error) {
+	return cli.listOffsets(topicName, partitions, at.UnixMilli())
+}
+
+// offsetRanges works out which offsets to read from each partition (stopping at the high watermarks as they are now):
+func (cli *CLI) offsetRanges(topicName string, from, to time.Time) ([]offsetRange, error) {
+	partitions, err := cli.topicPartitions(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start at the beginning (or the first message from the given time):
+	startOffsets, err := cli.listOffsets(topicName, partitions, kafka.FirstOffset)
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() {
+		fromOffsets, err := cli.offsetsForTime(topicName, partitions, from)
+		if err != nil {
+			return nil, err
+		}
+		for partition, offset := range fromOffsets {
+			if offset < 0 {
+				delete(startOffsets, partition)
+				continue
+			}
+			startOffsets[partition] = offset
+		}
+	}
+
+	// Stop at the high watermark (or the first message after the given time):
+	endOffsets, err := cli.listOffsets(topicName, partitions, kafka.LastOffset)
+	if err != nil {
+		return nil, err
+	}
+	if !to.IsZero() {
+		toOffsets, err := cli.offsetsForTime(topicName, partitions, to.Add(time.Millisecond))
+		if err != nil {
+			return nil, err
+		}
+		for partition, offset := range toOffsets {
+			if offset >= 0 && offset < endOffsets[partition] {
+				endOffsets[partition] = offset
+			}
+		}
+	}
+
+	// Only include partitions with something in range:
+	var offsetRanges []offsetRange
+	for _, partition := range partitions {
+		startOffset, ok := startOffsets[partition]
+		if !ok || startOffset >= endOffsets[partition] {
+			continue
+		}
+		offsetRanges = append(offsetRanges, offsetRange{
+			end:       endOffsets[partition],
+			partition: partition,
+			start:     startOffset,
+		})
+	}
+
+	return offsetRanges, nil
+}
+
+// readRange reads every message in a partition's range, handing each one to a callback.
+// It returns the offset it got up to (the end of the range, unless nothing arrived for the idle timeout before getting there):
+func (cli *CLI) readRange(topicName string, partitionRange offsetRange, idleTimeout time.Duration, callback func(message kafka.Message) error) (int64, error) {
+
+	// Get a reader for the partition (skipping messages from aborted transactions, which were never really there):
+	reader, err := cli.config.Kafka.PartitionReader(cli.logger, topicName, partitionRange.partition, partitionRange.start, configuration.ReaderOptions{IsolationLevel: kafka.ReadCommitted})
+	if err != nil {
+		return partitionRange.start, err
+	}
+	defer reader.Close()
+
+	for {
+		// The last offsets of a range can be transaction markers (which are never returned), so give up if nothing arrives
+		// (the caller decides what to make of stopping short, because a slow broker looks the same):
+		ctx, cancel := context.WithTimeout(context.Background(), idleTimeout)
+		message, err := reader.FetchMessage(ctx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return min(reader.Offset(), partitionRange.end), nil
+		}
+		if err != nil {
+			return reader.Offset(), err
+		}
+
+		if err := callback(message); err != nil {
+			return message.Offset, err
+		}
+
+		// Stop at the end of the range:
+		if message.Offset >= partitionRange.end-1 {
+			return partitionRange.end, nil
+		}
+	}
+}
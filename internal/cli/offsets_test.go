@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListedOffset(t *testing.T) {
+	watermarks := kafka.PartitionOffsets{FirstOffset: 100, LastOffset: 250}
+	assert.Equal(t, int64(100), listedOffset(watermarks, kafka.FirstOffset))
+	assert.Equal(t, int64(250), listedOffset(watermarks, kafka.LastOffset))
+
+	// Lookups by time give the first offset at or after the time:
+	at := int64(1700000000000)
+	found := kafka.PartitionOffsets{Offsets: map[int64]time.Time{180: time.UnixMilli(at)}}
+	assert.Equal(t, int64(180), listedOffset(found, at))
+
+	// Or -1 if nothing is that recent (whether the broker says so, or leaves it out):
+	notFound := kafka.PartitionOffsets{Offsets: map[int64]time.Time{-1: {}}}
+	assert.Equal(t, int64(-1), listedOffset(notFound, at))
+	assert.Equal(t, int64(-1), listedOffset(kafka.PartitionOffsets{}, at))
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/envelope"
+	"github.com/chrusty/kafka-cli/internal/serdes"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// Outputs which can be chosen with --output:
+const (
+	outputJSON  = "json"
+	outputValue = "value"
+)
+
+// Fields which can be selected with --fields:
+const (
+	fieldHeaders     = "headers"
+	fieldKey         = "key"
+	fieldOffset      = "offset"
+	fieldPartition   = "partition"
+	fieldTimestamp   = "timestamp"
+	fieldTopic       = "topic"
+	fieldTransaction = "transaction"
+	fieldValue       = "value"
+	fieldViolations  = "violations"
+)
+
+// record is a consumed message (with its key, value and headers decoded):
+type record struct {
+	Headers       map[string]string
+	Key           string
+	Offset        int64
+	Partition     int
+	Timestamp     time.Time
+	TimestampType string
+	Topic         string
+	Transaction   *envelope.Transaction
+	Value         string
+	Violations    []serdes.Violation
+	message       kafka.Message
+}
+
+// recordFormatter renders records for output:
+type recordFormatter interface {
+	format(r *record) (string, error)
+}
+
+// addOutputFlags adds the flags which choose how records are printed:
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("output", outputValue, "How to print each message [value, json] (json envelopes include all of the metadata, and can be read back by restore)")
+	cmd.PersistentFlags().StringSlice("fields", nil, "Print these fields (tab-separated) for each message [topic, partition, offset, timestamp, key, value, headers, violations, transaction]")
+	cmd.PersistentFlags().String("template", "", `Print each message with a Go template (eg '{{.Partition}}:{{.Offset}} {{.Key}} {{.Value | json "user.id"}}')`)
+}
+
+// recordFormatter prepares a formatter for the output given in our flags:
+func (cli *CLI) recordFormatter(cmd *cobra.Command) (recordFormatter, error) {
+
+	// Get the output flags:
+	fields, err := cmd.Flags().GetStringSlice("fields")
+	if err != nil {
+		return nil, err
+	}
+	templateText, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+
+	case templateText != "" && len(fields) > 0:
+		return nil, fmt.Errorf("--template and --fields can't be used together")
+
+	case output != outputValue && (templateText != "" || len(fields) > 0):
+		return nil, fmt.Errorf("--output can't be combined with --template or --fields")
+
+	case output == outputJSON:
+		return &jsonFormatter{}, nil
+
+	case output != outputValue:
+		return nil, fmt.Errorf("unknown output %s", output)
+
+	case templateText != "":
+		parsedTemplate, err := template.New("record").Funcs(templateFuncs).Parse(templateText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		return &templateFormatter{template: parsedTemplate}, nil
+
+	case len(fields) > 0:
+		for _, field := range fields {
+			switch field {
+			case fieldHeaders, fieldKey, fieldOffset, fieldPartition, fieldTimestamp, fieldTopic, fieldTransaction, fieldValue, fieldViolations:
+			default:
+				return nil, fmt.Errorf("unknown field %s", field)
+			}
+		}
+		return &fieldsFormatter{fields: fields}, nil
+
+	default:
+		return &valueFormatter{}, nil
+	}
+}
+
+// valueFormatter prints just the value (tagging any which failed validation or were aborted, and prefixing the topic if asked):
+type valueFormatter struct {
+	withTopic bool
+}
+
+func (vf *valueFormatter) format(r *record) (string, error) {
+	output := r.Value
+	if len(r.Violations) > 0 {
+		violations := make([]string, len(r.Violations))
+		for i, violation := range r.Violations {
+			violations[i] = violation.String()
+		}
+		output = fmt.Sprintf("[INVALID %s] %s", strings.Join(violations, "; "), output)
+	}
+	if r.Transaction != nil {
+		switch {
+		case r.Transaction.Control != "":
+			output = fmt.Sprintf("[%s marker producer=%d]", r.Transaction.Control, r.Transaction.ProducerID)
+		case r.Transaction.Aborted:
+			output = fmt.Sprintf("[ABORTED producer=%d] %s", r.Transaction.ProducerID, output)
+		}
+	}
+	if vf.withTopic {
+		output = r.Topic + "\t" + output
+	}
+	return output, nil
+}
+
+// jsonFormatter prints a JSON envelope (with all of the metadata, and binary-safe data):
+type jsonFormatter struct{}
+
+func (jf *jsonFormatter) format(r *record) (string, error) {
+	wrapped := envelope.New(r.message)
+	wrapped.Headers = r.Headers
+	wrapped.TimestampType = r.TimestampType
+	wrapped.Transaction = r.Transaction
+
+	// Include the decoded key and value (if they were decoded into something other than the raw text):
+	if r.message.Key != nil && r.Key != string(r.message.Key) {
+		wrapped.Key.SetDecoded(r.Key)
+	}
+	if r.message.Value != nil && r.Value != string(r.message.Value) {
+		wrapped.Value.SetDecoded(r.Value)
+	}
+	for _, violation := range r.Violations {
+		wrapped.Violations = append(wrapped.Violations, violation.String())
+	}
+
+	rendered, err := json.Marshal(wrapped)
+	return string(rendered), err
+}
+
+// fieldsFormatter prints a tab-separated selection of fields:
+type fieldsFormatter struct {
+	fields []string
+}
+
+func (ff *fieldsFormatter) format(r *record) (string, error) {
+	columns := make([]string, len(ff.fields))
+
+	for i, field := range ff.fields {
+		switch field {
+		case fieldHeaders:
+			columns[i] = formatHeaders(r.Headers)
+		case fieldKey:
+			columns[i] = r.Key
+		case fieldOffset:
+			columns[i] = strconv.FormatInt(r.Offset, 10)
+		case fieldPartition:
+			columns[i] = strconv.Itoa(r.Partition)
+		case fieldTimestamp:
+			columns[i] = r.Timestamp.Format(time.RFC3339Nano)
+		case fieldTopic:
+			columns[i] = r.Topic
+		case fieldTransaction:
+			columns[i] = formatTransaction(r.Transaction)
+		case fieldValue:
+			columns[i] = r.Value
+		case fieldViolations:
+			columns[i] = fmt.Sprint(r.Violations)
+		}
+	}
+
+	return strings.Join(columns, "\t"), nil
+}
+
+// templateFormatter prints records with a Go template:
+type templateFormatter struct {
+	template *template.Template
+}
+
+func (tf *templateFormatter) format(r *record) (string, error) {
+	var rendered bytes.Buffer
+	if err := tf.template.Execute(&rendered, r); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// templateFuncs are the extra functions available to templates:
+var templateFuncs = template.FuncMap{
+	"json": jsonField,
+}
+
+// formatHeaders renders headers as a sorted list of name=value pairs:
+func formatHeaders(headers map[string]string) string {
+	pairs := make([]string, 0, len(headers))
+	for name, value := range headers {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatTransaction renders what we know about a record's transaction (eg "aborted", "transactional" or "marker:COMMIT"):
+func formatTransaction(transaction *envelope.Transaction) string {
+	switch {
+	case transaction == nil:
+		return ""
+	case transaction.Control != "":
+		return "marker:" + transaction.Control
+	case transaction.Aborted:
+		return "aborted"
+	default:
+		return "transactional"
+	}
+}
+
+// jsonField extracts a field from a JSON document by a dotted path (eg "user.id" or "items.0.sku"):
+func jsonField(path, document string) (string, error) {
+	var parsed interface{}
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return "", fmt.Errorf("value is not JSON: %w", err)
+	}
+
+	// Walk the path:
+	for _, segment := range strings.Split(path, ".") {
+		switch node := parsed.(type) {
+		case map[string]interface{}:
+			parsed = node[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", nil
+			}
+			parsed = node[index]
+		default:
+			return "", nil
+		}
+	}
+
+	// Strings are printed bare, everything else as JSON:
+	switch node := parsed.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return node, nil
+	default:
+		rendered, err := json.Marshal(node)
+		return string(rendered), err
+	}
+}
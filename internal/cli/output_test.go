@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/serdes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFormatters(t *testing.T) {
+
+	// A decoded record:
+	testRecord := &record{
+		Headers:   map[string]string{"tenant": "x", "source": "api"},
+		Key:       "abc",
+		Offset:    1000,
+		Partition: 3,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Topic:     "orders",
+		Value:     `{"user":{"id":42,"name":"bob"},"items":[{"sku":"s-1"}]}`,
+	}
+
+	// Templates can pull fields out of JSON values:
+	parsedTemplate, err := template.New("record").Funcs(templateFuncs).Parse(`{{.Partition}}:{{.Offset}} {{.Key}} {{.Value | json "user.id"}} {{.Value | json "items.0.sku"}} {{index .Headers "tenant"}}`)
+	assert.NoError(t, err, "Error while parsing a template")
+	output, err := (&templateFormatter{template: parsedTemplate}).format(testRecord)
+	assert.NoError(t, err, "Error while formatting a record")
+	assert.Equal(t, "3:1000 abc 42 s-1 x", output)
+
+	// Fields are tab-separated:
+	output, err = (&fieldsFormatter{fields: []string{fieldPartition, fieldOffset, fieldTimestamp, fieldKey, fieldHeaders}}).format(testRecord)
+	assert.NoError(t, err, "Error while formatting a record")
+	assert.Equal(t, "3\t1000\t2024-01-02T03:04:05Z\tabc\tsource=api,tenant=x", output)
+
+	// Values which failed validation are tagged with each violation:
+	invalidRecord := &record{
+		Value:      `{"id":"x"}`,
+		Violations: []serdes.Violation{{Path: "/id", Message: "expected integer"}, {Path: "/name", Message: "missing"}},
+	}
+	output, err = (&valueFormatter{}).format(invalidRecord)
+	assert.NoError(t, err, "Error while formatting a record")
+	assert.Equal(t, `[INVALID /id: expected integer; /name: missing] {"id":"x"}`, output)
+}
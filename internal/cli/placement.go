@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"slices"
+	"sort"
+)
+
+// placementAudit describes how replicas and leaders are spread over the brokers and racks:
+type placementAudit struct {
+	BrokerLeaders  map[int]int
+	BrokerReplicas map[int]int
+	LeaderSkew     int
+	RackLeaders    map[string]int
+	RackReplicas   map[string]int
+	RackViolations []partitionState
+	ReplicaSkew    int
+}
+
+// auditPlacement checks some partitions for replicas sharing a rack (when there are racks to spare), and counts the replicas and leaders on each broker and rack.
+// Skew is the difference between the busiest and quietest brokers:
+func auditPlacement(state *clusterState, partitions []partitionState) *placementAudit {
+	audit := &placementAudit{
+		BrokerLeaders:  make(map[int]int),
+		BrokerReplicas: make(map[int]int),
+		RackLeaders:    make(map[string]int),
+		RackReplicas:   make(map[string]int),
+	}
+	rackCount := len(distinctRacks(state.Brokers, state.Racks))
+
+	for _, partition := range partitions {
+		if len(distinctRacks(partition.Replicas, state.Racks)) < min(len(partition.Replicas), rackCount) {
+			audit.RackViolations = append(audit.RackViolations, partition)
+		}
+		if partition.Leader >= 0 {
+			audit.BrokerLeaders[partition.Leader]++
+			audit.RackLeaders[state.Racks[partition.Leader]]++
+		}
+		for _, replica := range partition.Replicas {
+			audit.BrokerReplicas[replica]++
+			audit.RackReplicas[state.Racks[replica]]++
+		}
+	}
+
+	audit.LeaderSkew = skew(state.Brokers, audit.BrokerLeaders)
+	audit.ReplicaSkew = skew(state.Brokers, audit.BrokerReplicas)
+	return audit
+}
+
+// replicaBrokers returns the brokers in the metadata plus any which hold replicas of some partitions without being in it (eg because they're restarting),
+// so plans don't move every replica off a broker which is only briefly missing:
+func replicaBrokers(state *clusterState, partitions []partitionState) (brokers []int, missing []int) {
+	brokers = slices.Clone(state.Brokers)
+	for _, partition := range partitions {
+		for _, replica := range partition.Replicas {
+			if !slices.Contains(brokers, replica) {
+				brokers = append(brokers, replica)
+				missing = append(missing, replica)
+			}
+		}
+	}
+	sort.Ints(brokers)
+	sort.Ints(missing)
+	return brokers, missing
+}
+
+// skew returns the difference between the highest and lowest counts of some brokers:
+func skew(brokers []int, counts map[int]int) int {
+	if len(brokers) == 0 {
+		return 0
+	}
+	lowest, highest := counts[brokers[0]], counts[brokers[0]]
+	for _, broker := range brokers {
+		lowest, highest = min(lowest, counts[broker]), max(highest, counts[broker])
+	}
+	return highest - lowest
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditPlacement(t *testing.T) {
+	state := &clusterState{
+		Brokers: []int{1, 2, 3, 4},
+		Racks:   map[int]string{1: "a", 2: "a", 3: "b", 4: "b"},
+	}
+	partitions := []partitionState{
+		{Leader: 1, Partition: 0, Replicas: []int{1, 2}, Topic: "orders"},
+		{Leader: 1, Partition: 1, Replicas: []int{1, 3}, Topic: "orders"},
+		{Leader: 3, Partition: 2, Replicas: []int{3, 1}, Topic: "orders"},
+		{Leader: -1, Partition: 3, Replicas: []int{4}, Topic: "orders"},
+	}
+
+	audit := auditPlacement(state, partitions)
+
+	// Only the partition with both replicas on rack a is a violation (one replica can't span racks):
+	assert.Len(t, audit.RackViolations, 1)
+	assert.Equal(t, 0, audit.RackViolations[0].Partition)
+
+	// Broker 1 holds 3 replicas and leads 2 partitions, while broker 4 holds 1 replica and leads nothing:
+	assert.Equal(t, map[int]int{1: 3, 2: 1, 3: 2, 4: 1}, audit.BrokerReplicas)
+	assert.Equal(t, map[string]int{"a": 2, "b": 1}, audit.RackLeaders)
+	assert.Equal(t, map[string]int{"a": 4, "b": 3}, audit.RackReplicas)
+	assert.Equal(t, 2, audit.ReplicaSkew)
+	assert.Equal(t, 2, audit.LeaderSkew)
+}
+
+func TestReplicaBrokers(t *testing.T) {
+	state := &clusterState{Brokers: []int{3, 1}}
+	partitions := []partitionState{
+		{Partition: 0, Replicas: []int{1, 2}, Topic: "orders"},
+		{Partition: 1, Replicas: []int{2, 3}, Topic: "orders"},
+	}
+
+	// Broker 2 is restarting, so it isn't in the metadata but still holds replicas:
+	brokers, missing := replicaBrokers(state, partitions)
+	assert.Equal(t, []int{1, 2, 3}, brokers)
+	assert.Equal(t, []int{2}, missing)
+	assert.Equal(t, []int{3, 1}, state.Brokers)
+}
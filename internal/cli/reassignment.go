@@ -0,0 +1,328 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+)
+
+// The version of Kafka's reassignment JSON format:
+const reassignmentVersion = 1
+
+// reassignmentFile is Kafka's reassignment JSON (as used by kafka-reassign-partitions.sh):
+type reassignmentFile struct {
+	Partitions []partitionAssignment `json:"partitions"`
+	Version    int                   `json:"version"`
+}
+
+// partitionAssignment is the replica set of one partition (the first replica is the preferred leader):
+type partitionAssignment struct {
+	LogDirs   []string `json:"log_dirs,omitempty"`
+	Partition int      `json:"partition"`
+	Replicas  []int    `json:"replicas"`
+	Topic     string   `json:"topic"`
+}
+
+// topicsToMoveFile is Kafka's topics-to-move JSON (eg {"version":1,"topics":[{"topic":"orders"}]}):
+type topicsToMoveFile struct {
+	Topics []struct {
+		Topic string `json:"topic"`
+	} `json:"topics"`
+	Version int `json:"version"`
+}
+
+// loadReassignmentFile reads a reassignment JSON file:
+func loadReassignmentFile(path string) (*reassignmentFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reassignment := &reassignmentFile{}
+	if err := json.Unmarshal(data, reassignment); err != nil {
+		return nil, fmt.Errorf("invalid reassignment JSON: %w", err)
+	}
+	for _, assignment := range reassignment.Partitions {
+		if assignment.Topic == "" || len(assignment.Replicas) == 0 {
+			return nil, fmt.Errorf("invalid reassignment JSON: every partition needs a topic and replicas")
+		}
+	}
+	return reassignment, nil
+}
+
+// loadTopicsToMoveFile reads the topic names from a topics-to-move JSON file:
+func loadTopicsToMoveFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	topicsToMove := &topicsToMoveFile{}
+	if err := json.Unmarshal(data, topicsToMove); err != nil {
+		return nil, fmt.Errorf("invalid topics-to-move JSON: %w", err)
+	}
+
+	topicNames := make([]string, len(topicsToMove.Topics))
+	for i, topic := range topicsToMove.Topics {
+		topicNames[i] = topic.Topic
+	}
+	return topicNames, nil
+}
+
+// newReassignmentFile sorts some assignments into a reassignment file:
+func newReassignmentFile(assignments []partitionAssignment) *reassignmentFile {
+	sorted := append([]partitionAssignment(nil), assignments...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Topic != sorted[j].Topic {
+			return sorted[i].Topic < sorted[j].Topic
+		}
+		return sorted[i].Partition < sorted[j].Partition
+	})
+	return &reassignmentFile{Partitions: sorted, Version: reassignmentVersion}
+}
+
+// saveReassignmentFile writes a reassignment JSON file:
+func saveReassignmentFile(path string, reassignment *reassignmentFile) error {
+	data, err := reassignment.JSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(data+"\n"), 0o644)
+}
+
+// JSON renders the file the way Kafka's tools do (on one line):
+func (rf *reassignmentFile) JSON() (string, error) {
+	data, err := json.Marshal(rf)
+	return string(data), err
+}
+
+// replicaPlanner works out balanced replica sets, moving as few replicas as it can:
+type replicaPlanner struct {
+	brokers []int
+	leaders map[int]int
+	racks   map[int]string
+	load    map[int]int
+}
+
+// planReassignment spreads partitions over the given brokers (with their racks, which can be blank) as evenly as possible.
+// Replicas stay where they are unless their broker is going, the replication factor is changing (0 keeps it), or a broker has too many:
+func planReassignment(current []partitionAssignment, brokers []int, racks map[int]string, replicationFactor int) ([]partitionAssignment, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no brokers to assign replicas to")
+	}
+
+	planner := &replicaPlanner{
+		brokers: append([]int(nil), brokers...),
+		leaders: make(map[int]int),
+		racks:   racks,
+		load:    make(map[int]int),
+	}
+	sort.Ints(planner.brokers)
+
+	// Keep the replicas which are on brokers we're keeping (up to the replication factor):
+	rackCount := len(distinctRacks(planner.brokers, racks))
+	proposed := make([]partitionAssignment, len(current))
+	for i, assignment := range current {
+		wanted := replicationFactor
+		if wanted <= 0 {
+			wanted = len(assignment.Replicas)
+		}
+		if wanted > len(planner.brokers) {
+			return nil, fmt.Errorf("replication factor %d is more than the %d brokers available", wanted, len(planner.brokers))
+		}
+
+		var kept []int
+		for _, replica := range assignment.Replicas {
+			if slices.Contains(planner.brokers, replica) {
+				kept = append(kept, replica)
+			}
+		}
+
+		// Keep replicas on different racks first, then ones sharing a rack (unless there are racks to spare):
+		var replicas []int
+		for _, replica := range kept {
+			if len(replicas) < wanted && planner.newRack(replica, replicas) {
+				replicas = append(replicas, replica)
+			}
+		}
+		spareRacks := max(min(wanted, rackCount)-len(distinctRacks(replicas, racks)), 0)
+		for _, replica := range kept {
+			if len(replicas) < wanted-spareRacks && !slices.Contains(replicas, replica) {
+				replicas = append(replicas, replica)
+			}
+		}
+		slices.SortStableFunc(replicas, func(a, b int) int {
+			return slices.Index(kept, a) - slices.Index(kept, b)
+		})
+		for _, replica := range replicas {
+			planner.load[replica]++
+		}
+
+		// Replicas still to be placed are -1 for now:
+		for len(replicas) < wanted {
+			replicas = append(replicas, -1)
+		}
+
+		proposed[i] = partitionAssignment{
+			Partition: assignment.Partition,
+			Replicas:  replicas,
+			Topic:     assignment.Topic,
+		}
+	}
+
+	// Fill in the missing replicas (on the least loaded brokers, preferring racks the partition isn't on yet):
+	for i := range proposed {
+		for j, replica := range proposed[i].Replicas {
+			if replica >= 0 {
+				continue
+			}
+			broker := planner.leastLoaded(proposed[i].Replicas)
+			proposed[i].Replicas[j] = broker
+			planner.load[broker]++
+		}
+	}
+
+	// Move replicas from the most loaded brokers to the least loaded ones until they're within one of each other:
+	for moves := 0; moves < len(proposed)*len(planner.brokers); moves++ {
+		if !planner.moveOne(proposed) {
+			break
+		}
+	}
+
+	// Spread the preferred leaders out too:
+	ideal := (len(proposed) + len(planner.brokers) - 1) / len(planner.brokers)
+	for i := range proposed {
+		replicas := proposed[i].Replicas
+		leader := 0
+		if planner.leaders[replicas[0]] >= ideal {
+			for j := range replicas {
+				if planner.leaders[replicas[j]] < planner.leaders[replicas[leader]] {
+					leader = j
+				}
+			}
+		}
+		replicas[0], replicas[leader] = replicas[leader], replicas[0]
+		planner.leaders[replicas[0]]++
+	}
+
+	return proposed, nil
+}
+
+// leastLoaded picks the broker with the fewest replicas which isn't already in a replica set (preferring new racks):
+func (rp *replicaPlanner) leastLoaded(replicas []int) int {
+	best := -1
+	for _, broker := range rp.brokers {
+		if slices.Contains(replicas, broker) {
+			continue
+		}
+		if best < 0 || rp.better(broker, best, replicas) {
+			best = broker
+		}
+	}
+	return best
+}
+
+// better reports whether a candidate is a better home than the current best for another replica of a partition:
+func (rp *replicaPlanner) better(candidate, best int, replicas []int) bool {
+	candidateNewRack, bestNewRack := rp.newRack(candidate, replicas), rp.newRack(best, replicas)
+	if candidateNewRack != bestNewRack {
+		return candidateNewRack
+	}
+	return rp.load[candidate] < rp.load[best]
+}
+
+// newRack reports whether a broker's rack isn't used by any of a partition's replicas yet (brokers without racks count as new):
+func (rp *replicaPlanner) newRack(broker int, replicas []int) bool {
+	rack := rp.racks[broker]
+	if rack == "" {
+		return true
+	}
+	for _, replica := range replicas {
+		if replica != broker && rp.racks[replica] == rack {
+			return false
+		}
+	}
+	return true
+}
+
+// moveOne moves a replica from the most loaded broker to the least loaded one (reporting false once they're balanced):
+func (rp *replicaPlanner) moveOne(proposed []partitionAssignment) bool {
+	most, least := rp.brokers[0], rp.brokers[0]
+	for _, broker := range rp.brokers {
+		if rp.load[broker] > rp.load[most] {
+			most = broker
+		}
+		if rp.load[broker] < rp.load[least] {
+			least = broker
+		}
+	}
+	if rp.load[most]-rp.load[least] <= 1 {
+		return false
+	}
+
+	// Find a replica to move (avoiding preferred leaders where we can):
+	candidate, candidateIndex := -1, -1
+	for i := range proposed {
+		replicas := proposed[i].Replicas
+		index := slices.Index(replicas, most)
+		if index < 0 || slices.Contains(replicas, least) {
+			continue
+		}
+
+		// Rack diversity comes before balance:
+		if rp.racks[most] != rp.racks[least] && !rp.rackAfterMove(replicas, index, least) {
+			continue
+		}
+		if candidate < 0 || (index > 0 && candidateIndex == 0) {
+			candidate, candidateIndex = i, index
+		}
+	}
+	if candidate < 0 {
+		return false
+	}
+
+	proposed[candidate].Replicas[candidateIndex] = least
+	rp.load[most]--
+	rp.load[least]++
+	return true
+}
+
+// rackAfterMove reports whether a replica set would still have no two replicas on the same rack after a move:
+func (rp *replicaPlanner) rackAfterMove(replicas []int, index, broker int) bool {
+	moved := append([]int(nil), replicas...)
+	moved[index] = broker
+	return rp.newRack(broker, moved)
+}
+
+// distinctRacks returns the (non-blank) racks some brokers are on:
+func distinctRacks(brokers []int, racks map[int]string) []string {
+	var distinct []string
+	for _, broker := range brokers {
+		if rack := racks[broker]; rack != "" && !slices.Contains(distinct, rack) {
+			distinct = append(distinct, rack)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct
+}
+
+// changedAssignments returns the proposed assignments which differ from the current ones (including the order of replicas):
+func changedAssignments(current, proposed []partitionAssignment) []partitionAssignment {
+	currentReplicas := make(map[string]map[int][]int)
+	for _, assignment := range current {
+		if currentReplicas[assignment.Topic] == nil {
+			currentReplicas[assignment.Topic] = make(map[int][]int)
+		}
+		currentReplicas[assignment.Topic][assignment.Partition] = assignment.Replicas
+	}
+
+	var changed []partitionAssignment
+	for _, assignment := range proposed {
+		if !slices.Equal(currentReplicas[assignment.Topic][assignment.Partition], assignment.Replicas) {
+			changed = append(changed, assignment)
+		}
+	}
+	return changed
+}
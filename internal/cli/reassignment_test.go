@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanReassignmentAddBroker(t *testing.T) {
+	var current []partitionAssignment
+	for partition := 0; partition < 6; partition++ {
+		current = append(current, partitionAssignment{Topic: "orders", Partition: partition, Replicas: []int{1 + partition%3, 1 + (partition+1)%3}})
+	}
+
+	// A fourth broker takes its share of the replicas and leaders:
+	proposed, err := planReassignment(current, []int{1, 2, 3, 4}, nil, 0)
+	assert.NoError(t, err, "Error while planning a reassignment")
+	replicas, leaders := brokerLoad(proposed)
+	assert.Equal(t, map[int]int{1: 3, 2: 3, 3: 3, 4: 3}, replicas)
+	for broker := 1; broker <= 4; broker++ {
+		assert.LessOrEqual(t, leaders[broker], 2)
+	}
+
+	// Only some partitions have to move:
+	changed := changedAssignments(current, proposed)
+	assert.NotEmpty(t, changed)
+	assert.Less(t, len(changed), len(current))
+}
+
+func TestPlanReassignmentDecommission(t *testing.T) {
+	current := []partitionAssignment{
+		{Topic: "orders", Partition: 0, Replicas: []int{1, 2, 3}},
+		{Topic: "orders", Partition: 1, Replicas: []int{2, 3, 4}},
+		{Topic: "orders", Partition: 2, Replicas: []int{3, 4, 1}},
+		{Topic: "orders", Partition: 3, Replicas: []int{4, 1, 2}},
+	}
+	racks := map[int]string{1: "a", 2: "b", 3: "c", 4: "a", 5: "b"}
+
+	// Broker 4 goes, and its replicas land on other racks:
+	proposed, err := planReassignment(current, []int{1, 2, 3, 5}, racks, 0)
+	assert.NoError(t, err, "Error while planning a reassignment")
+	for _, assignment := range proposed {
+		assert.Len(t, assignment.Replicas, 3)
+		assert.NotContains(t, assignment.Replicas, 4)
+		seenRacks := make(map[string]bool)
+		for _, replica := range assignment.Replicas {
+			assert.False(t, seenRacks[racks[replica]], "Two replicas on rack %s in %v", racks[replica], assignment.Replicas)
+			seenRacks[racks[replica]] = true
+		}
+	}
+
+	// The new broker takes as much as the racks allow:
+	replicas, _ := brokerLoad(proposed)
+	assert.Greater(t, replicas[5], 0)
+}
+
+func TestPlanReassignmentReplicationFactor(t *testing.T) {
+	current := []partitionAssignment{
+		{Topic: "orders", Partition: 0, Replicas: []int{1}},
+		{Topic: "orders", Partition: 1, Replicas: []int{2}},
+	}
+
+	proposed, err := planReassignment(current, []int{1, 2, 3}, nil, 2)
+	assert.NoError(t, err, "Error while planning a reassignment")
+	for _, assignment := range proposed {
+		assert.Len(t, assignment.Replicas, 2)
+		assert.False(t, assignment.Replicas[0] == assignment.Replicas[1])
+	}
+	assert.True(t, slices.Contains(proposed[0].Replicas, 1))
+
+	_, err = planReassignment(current, []int{1, 2, 3}, nil, 4)
+	assert.Error(t, err)
+}
+
+func TestReassignmentFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// Kafka's format comes through (log dirs included):
+	path := filepath.Join(dir, "plan.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version":1,"partitions":[{"topic":"orders","partition":1,"replicas":[2,3],"log_dirs":["any","any"]},{"topic":"orders","partition":0,"replicas":[1,2]}]}`), 0644))
+	plan, err := loadReassignmentFile(path)
+	assert.NoError(t, err, "Error while loading a reassignment file")
+	assert.Len(t, plan.Partitions, 2)
+	assert.Equal(t, []string{"any", "any"}, plan.Partitions[0].LogDirs)
+
+	// And goes back out sorted:
+	output, err := newReassignmentFile(plan.Partitions).JSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"partitions":[{"partition":0,"replicas":[1,2],"topic":"orders"},{"log_dirs":["any","any"],"partition":1,"replicas":[2,3],"topic":"orders"}],"version":1}`, output)
+
+	// Topics-to-move files work too:
+	topicsPath := filepath.Join(dir, "topics.json")
+	assert.NoError(t, os.WriteFile(topicsPath, []byte(`{"version":1,"topics":[{"topic":"orders"},{"topic":"payments"}]}`), 0644))
+	topicNames, err := loadTopicsToMoveFile(topicsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orders", "payments"}, topicNames)
+
+	// Partitions need replicas:
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version":1,"partitions":[{"topic":"orders","partition":0}]}`), 0644))
+	_, err = loadReassignmentFile(path)
+	assert.Error(t, err)
+}
+
+func TestThrottledReplicas(t *testing.T) {
+	current := []partitionAssignment{
+		{Topic: "orders", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "orders", Partition: 1, Replicas: []int{2, 3}},
+	}
+	proposed := []partitionAssignment{
+		{Topic: "orders", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "orders", Partition: 1, Replicas: []int{2, 4}},
+	}
+
+	// Only the moving partition is throttled (its current replicas lead, the new one follows):
+	leaders, followers, brokers := throttledReplicas(current, proposed)
+	assert.Equal(t, map[string][]string{"orders": {"1:2", "1:3"}}, leaders)
+	assert.Equal(t, map[string][]string{"orders": {"1:4"}}, followers)
+	assert.Equal(t, []int{2, 3, 4}, brokers)
+}
+
+func TestPlanReassignmentRackDiversity(t *testing.T) {
+	current := []partitionAssignment{
+		{Topic: "orders", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "orders", Partition: 1, Replicas: []int{3, 4}},
+		{Topic: "orders", Partition: 2, Replicas: []int{1, 3}},
+		{Topic: "orders", Partition: 3, Replicas: []int{4, 2}},
+	}
+	racks := map[int]string{1: "a", 2: "a", 3: "b", 4: "b"}
+
+	// Replicas sharing a rack are split up (keeping the preferred leader), and the rest stay put:
+	proposed, err := planReassignment(current, []int{1, 2, 3, 4}, racks, 0)
+	assert.NoError(t, err, "Error while planning a reassignment")
+	for _, assignment := range proposed {
+		assert.NotEqual(t, racks[assignment.Replicas[0]], racks[assignment.Replicas[1]], "Both replicas on one rack in %v", assignment.Replicas)
+	}
+	assert.Equal(t, 1, proposed[0].Replicas[0])
+	assert.Equal(t, 3, proposed[1].Replicas[0])
+	assert.Equal(t, []int{1, 3}, proposed[2].Replicas)
+	assert.Equal(t, []int{4, 2}, proposed[3].Replicas)
+
+	// Lowering the replication factor drops a replica sharing a rack:
+	proposed, err = planReassignment([]partitionAssignment{{Topic: "orders", Partition: 0, Replicas: []int{1, 2, 3}}}, []int{1, 2, 3, 4}, racks, 2)
+	assert.NoError(t, err, "Error while planning a reassignment")
+	assert.ElementsMatch(t, []int{1, 3}, proposed[0].Replicas)
+}
@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// headerRewrite strips and sets headers on replayed messages:
+type headerRewrite struct {
+	set   []kafka.Header
+	strip map[string]bool
+}
+
+// newHeaderRewrite prepares a headerRewrite from name=value pairs to set, and names to strip:
+func newHeaderRewrite(set, strip []string) (*headerRewrite, error) {
+	rewrite := &headerRewrite{strip: make(map[string]bool)}
+
+	for _, name := range strip {
+		rewrite.strip[name] = true
+	}
+	for _, header := range set {
+		name, value, found := strings.Cut(header, "=")
+		if !found {
+			return nil, fmt.Errorf("headers must be given as name=value (not %s)", header)
+		}
+		rewrite.set = append(rewrite.set, kafka.Header{Key: name, Value: []byte(value)})
+	}
+
+	return rewrite, nil
+}
+
+// apply returns a message's headers with the stripped (and set) ones removed, then the set ones added:
+func (hr *headerRewrite) apply(headers []kafka.Header) []kafka.Header {
+	replaced := make(map[string]bool, len(hr.set))
+	for _, header := range hr.set {
+		replaced[header.Key] = true
+	}
+
+	rewritten := make([]kafka.Header, 0, len(headers)+len(hr.set))
+	for _, header := range headers {
+		if hr.strip[header.Key] || replaced[header.Key] {
+			continue
+		}
+		rewritten = append(rewritten, header)
+	}
+	return append(rewritten, hr.set...)
+}
+
+// replayDestination works out where a message goes (a fixed topic, or the last value of a header):
+func replayDestination(message kafka.Message, toTopic, toHeader string) (string, error) {
+	if toTopic != "" {
+		return toTopic, nil
+	}
+
+	var destination string
+	for _, header := range message.Headers {
+		if header.Key == toHeader {
+			destination = string(header.Value)
+		}
+	}
+	if destination == "" {
+		return "", fmt.Errorf("no %s header", toHeader)
+	}
+	return destination, nil
+}
+
+// replayProgress tracks how far into each partition every message has been replayed (records can only be deleted up to the first one which wasn't):
+type replayProgress struct {
+	blocked   map[int]bool
+	deletable map[int]int64
+}
+
+func newReplayProgress() *replayProgress {
+	return &replayProgress{
+		blocked:   make(map[int]bool),
+		deletable: make(map[int]int64),
+	}
+}
+
+// replayed records that a message was replayed:
+func (rp *replayProgress) replayed(message kafka.Message) {
+	if !rp.blocked[message.Partition] {
+		rp.deletable[message.Partition] = message.Offset + 1
+	}
+}
+
+// skipped records that a message wasn't replayed (so nothing after it can be deleted):
+func (rp *replayProgress) skipped(message kafka.Message) {
+	rp.blocked[message.Partition] = true
+}
+
+func (cli *CLI) initReplay() {
+	replayCommand := cli.replayCommand()
+	replayCommand.PersistentFlags().Int("batch-size", 500, "How many messages to publish at once")
+	replayCommand.PersistentFlags().Bool("delete", false, "Delete the replayed messages from the dead-letter topic afterwards (up to the first message which wasn't replayed in each partition)")
+	replayCommand.PersistentFlags().Bool("dry-run", false, "Show what would be replayed (and where to), without publishing anything")
+	replayCommand.PersistentFlags().String("filter", "", `Only replay messages matching an expression (eg 'headers["x-error"] contains "timeout"')`)
+	replayCommand.PersistentFlags().StringArray("set-header", nil, "Set a header on replayed messages, as name=value (repeatable)")
+	replayCommand.PersistentFlags().StringArray("strip-header", nil, "Remove a header from replayed messages (repeatable)")
+	replayCommand.PersistentFlags().String("to", "", "The topic to replay messages to")
+	replayCommand.PersistentFlags().String("to-header", "", "Replay each message to the topic named in this header (eg x-original-topic)")
+	addDeserializerFlags(replayCommand)
+	cli.SetCommand("replay", "root", replayCommand)
+}
+
+// replayCommand deals with replaying messages from dead-letter topics:
+func (cli *CLI) replayCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:        "replay <dlq-topic>",
+		Short:      "Replay messages from a dead-letter topic back to a topic (or the topic named in a header)",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"dlq-topic"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the destination flags:
+			toTopic, err := cmd.Flags().GetString("to")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "to").Fatal("Unable to get flag")
+			}
+			toHeader, err := cmd.Flags().GetString("to-header")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "to-header").Fatal("Unable to get flag")
+			}
+			if (toTopic == "") == (toHeader == "") {
+				cli.logger.Fatal("Give exactly one of --to or --to-header")
+			}
+
+			// Get the header flags:
+			setHeaders, err := cmd.Flags().GetStringArray("set-header")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "set-header").Fatal("Unable to get flag")
+			}
+			stripHeaders, err := cmd.Flags().GetStringArray("strip-header")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "strip-header").Fatal("Unable to get flag")
+			}
+			rewrite, err := newHeaderRewrite(setHeaders, stripHeaders)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Invalid headers")
+			}
+
+			// Get the other flags:
+			batchSize, err := cmd.Flags().GetInt("batch-size")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "batch-size").Fatal("Unable to get flag")
+			}
+			if batchSize < 1 {
+				batchSize = 1
+			}
+			deleteReplayed, err := cmd.Flags().GetBool("delete")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "delete").Fatal("Unable to get flag")
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "dry-run").Fatal("Unable to get flag")
+			}
+
+			// Get the filter flag:
+			filterExpression, err := cmd.Flags().GetString("filter")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "filter").Fatal("Unable to get flag")
+			}
+			filter, err := newMessageFilter(filterExpression)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a filter")
+			}
+
+			// Get deserializers for the message keys, values and headers:
+			deserializers, err := cli.messageDeserializers(cmd)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare deserializers")
+			}
+
+			// Get the topic name:
+			topicName := args[0]
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Replaying topic: %s", topicName)
+
+			// Work out which offsets to replay in each partition (everything up to the high watermarks as they are now):
+			offsetRanges, err := cli.offsetRanges(topicName, time.Time{}, time.Time{})
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to determine which offsets to replay")
+			}
+
+			// Get a producer (the topic is set per message):
+			producer, err := cli.config.Kafka.Producer(cli.logger, "")
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a Kafka producer")
+			}
+			producer.Balancer = kafka.Murmur2Balancer{}
+			producer.BatchSize = batchSize
+			producer.BatchTimeout = 10 * time.Millisecond
+			defer producer.Close()
+
+			// Replay each partition in order:
+			progress := newReplayProgress()
+			destinations := make(map[string]int)
+			var messagesScanned, messagesSkipped int
+			for _, partitionRange := range offsetRanges {
+				var batch, pending []kafka.Message
+				publish := func() error {
+					if len(batch) > 0 && !dryRun {
+						if err := producer.WriteMessages(context.TODO(), batch...); err != nil {
+							return err
+						}
+					}
+					for _, message := range pending {
+						progress.replayed(message)
+					}
+					batch, pending = batch[:0], pending[:0]
+					return nil
+				}
+
+				// Messages from aborted transactions are never replayed (or counted as replayed when deleting):
+				reached, err := cli.readRange(topicName, partitionRange, readRangeIdleTimeout, func(message kafka.Message) error {
+					messagesScanned++
+
+					// Skip messages which don't match our filter:
+					if filter != nil {
+						decoded, err := deserializers.record(message)
+						if decoded == nil {
+							cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Debug("Unable to deserialize a message")
+							decoded = deserializers.rawRecord(message)
+						}
+						decoded.TimestampType = cli.timestampType(message.Topic)
+						matched, err := filter.match(decoded)
+						if err != nil {
+							cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Debug("Unable to evaluate the filter")
+						}
+						if !matched {
+							messagesSkipped++
+							progress.skipped(message)
+							return nil
+						}
+					}
+
+					// Work out where it goes:
+					destination, err := replayDestination(message, toTopic, toHeader)
+					if err != nil {
+						cli.logger.WithError(err).WithField("offset", message.Offset).WithField("partition", message.Partition).Warn("Unable to determine where to replay a message")
+						messagesSkipped++
+						progress.skipped(message)
+						return nil
+					}
+					if destination == topicName {
+						cli.logger.WithField("offset", message.Offset).WithField("partition", message.Partition).Warn("Not replaying a message back onto the dead-letter topic")
+						messagesSkipped++
+						progress.skipped(message)
+						return nil
+					}
+					destinations[destination]++
+
+					if dryRun {
+						cli.logger.
+							WithField("destination", destination).
+							WithField("headers", deserializers.deserializeHeaders(rewrite.apply(message.Headers))).
+							WithField("key", deserializers.deserializeKey(message.Key)).
+							WithField("offset", message.Offset).
+							WithField("partition", message.Partition).
+							Info("Would replay message")
+					}
+
+					batch = append(batch, kafka.Message{
+						Headers: rewrite.apply(message.Headers),
+						Key:     message.Key,
+						Topic:   destination,
+						Value:   message.Value,
+					})
+					pending = append(pending, message)
+					if len(batch) >= batchSize {
+						return publish()
+					}
+					return nil
+				})
+				if err == nil {
+					err = publish()
+				}
+				if err != nil {
+					cli.logger.WithError(err).WithField("partition", partitionRange.partition).Fatal("Unable to replay partition")
+				}
+				if partitionRange.stoppedShort(reached) {
+					cli.logger.
+						WithField("end", partitionRange.end).
+						WithField("partition", partitionRange.partition).
+						WithField("reached", reached).
+						Warn("Replay of partition stopped before the end of its range (the rest may only be transaction markers, or the broker is slow)")
+				}
+			}
+
+			for destination, messages := range destinations {
+				cli.logger.WithField("destination", destination).WithField("messages", messages).WithField("dry_run", dryRun).Info("Replayed messages")
+			}
+
+			// Delete what was replayed:
+			if deleteReplayed && len(progress.deletable) > 0 {
+				if dryRun {
+					for partition, offset := range progress.deletable {
+						cli.logger.WithField("before_offset", offset).WithField("partition", partition).Info("Would delete records")
+					}
+				} else {
+					lowWatermarks, err := cli.deleteRecords(topicName, progress.deletable)
+					for partition, lowWatermark := range lowWatermarks {
+						cli.logger.WithField("low_watermark", lowWatermark).WithField("partition", partition).Info("Deleted replayed records")
+					}
+					if err != nil {
+						cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to delete the replayed records")
+					}
+				}
+			}
+
+			cli.logger.
+				WithField("dry_run", dryRun).
+				WithField("replayed", messagesScanned-messagesSkipped).
+				WithField("scanned", messagesScanned).
+				WithField("skipped", messagesSkipped).
+				WithField("topic", topicName).
+				Info("Replay complete")
+		},
+	}
+}
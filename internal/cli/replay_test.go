@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderRewrite(t *testing.T) {
+	rewrite, err := newHeaderRewrite([]string{"x-replayed=true", "tenant=y"}, []string{"x-error"})
+	assert.NoError(t, err, "Error while preparing a header rewrite")
+
+	// Stripped headers go, set headers replace any existing ones (and are added at the end):
+	rewritten := rewrite.apply([]kafka.Header{
+		{Key: "tenant", Value: []byte("x")},
+		{Key: "x-error", Value: []byte("timeout")},
+		{Key: "trace", Value: []byte("abc")},
+	})
+	assert.Equal(t, []kafka.Header{
+		{Key: "trace", Value: []byte("abc")},
+		{Key: "x-replayed", Value: []byte("true")},
+		{Key: "tenant", Value: []byte("y")},
+	}, rewritten)
+
+	_, err = newHeaderRewrite([]string{"no-value"}, nil)
+	assert.Error(t, err)
+}
+
+func TestReplayDestination(t *testing.T) {
+	message := kafka.Message{Headers: []kafka.Header{{Key: "x-original-topic", Value: []byte("orders")}}}
+
+	destination, err := replayDestination(message, "payments", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "payments", destination)
+
+	destination, err = replayDestination(message, "", "x-original-topic")
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", destination)
+
+	_, err = replayDestination(kafka.Message{}, "", "x-original-topic")
+	assert.Error(t, err)
+}
+
+func TestReplayProgress(t *testing.T) {
+	progress := newReplayProgress()
+
+	// Records can only be deleted up to the first message which wasn't replayed:
+	progress.replayed(kafka.Message{Partition: 0, Offset: 10})
+	progress.replayed(kafka.Message{Partition: 0, Offset: 11})
+	progress.skipped(kafka.Message{Partition: 0, Offset: 12})
+	progress.replayed(kafka.Message{Partition: 0, Offset: 13})
+	progress.replayed(kafka.Message{Partition: 1, Offset: 5})
+	assert.Equal(t, map[int]int64{0: 12, 1: 6}, progress.deletable)
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/envelope"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+// The longest line we'll accept in a segment file:
+const segmentMaxLineBytes = 64 * 1024 * 1024
+
+// partitionBalancer sends every message to the partition it already has (so partition mapping is preserved):
+type partitionBalancer struct{}
+
+func (partitionBalancer) Balance(message kafka.Message, partitions ...int) int {
+	return message.Partition
+}
+
+// readSegment reads the messages (one envelope per line) from a segment file, handing each one to a callback:
+func readSegment(path string, callback func(message kafka.Message) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, segmentMaxLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		messageEnvelope := &envelope.Envelope{}
+		if err := json.Unmarshal(scanner.Bytes(), messageEnvelope); err != nil {
+			return fmt.Errorf("invalid envelope on line %d: %w", line, err)
+		}
+		message, err := messageEnvelope.Message()
+		if err != nil {
+			return fmt.Errorf("invalid envelope on line %d: %w", line, err)
+		}
+		if err := callback(message); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// createTopic creates a topic with some configs (reporting false if it already exists):
+func (cli *CLI) createTopic(topicName string, partitions, replicationFactor int, configs map[string]string) (bool, error) {
+	configEntries := make([]kafka.ConfigEntry, 0, len(configs))
+	for name, value := range configs {
+		configEntries = append(configEntries, kafka.ConfigEntry{ConfigName: name, ConfigValue: value})
+	}
+
+	response, err := cli.adminClient.CreateTopics(context.TODO(), &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{
+			{
+				ConfigEntries:     configEntries,
+				NumPartitions:     partitions,
+				ReplicationFactor: replicationFactor,
+				Topic:             topicName,
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch err := response.Errors[topicName]; {
+	case errors.Is(err, kafka.TopicAlreadyExists):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	cli.logger.
+		WithField("configs", len(configEntries)).
+		WithField("partitions", partitions).
+		WithField("replication_factor", replicationFactor).
+		WithField("topic", topicName).
+		Info("Topic created")
+	return true, nil
+}
+
+func (cli *CLI) initRestore() {
+	restoreCommand := cli.restoreCommand()
+	restoreCommand.PersistentFlags().Bool("allow-incomplete", false, "Restore a backup even if some of its partitions stopped before the end of their range")
+	restoreCommand.PersistentFlags().Int("batch-size", 1000, "How many messages to publish at once")
+	restoreCommand.PersistentFlags().Int("replication-factor", 0, "The replication factor to create the topic with (defaults to the original)")
+	restoreCommand.PersistentFlags().String("topic", "", "The topic to restore to (defaults to the original)")
+	cli.SetCommand("restore", "root", restoreCommand)
+}
+
+// restoreCommand deals with restoring topics from local files:
+func (cli *CLI) restoreCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:        "restore <dir>",
+		Short:      "Recreate a topic from a backup, and republish its messages (to the same partitions, with the same timestamps)",
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"dir"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Get the allow-incomplete flag:
+			allowIncomplete, err := cmd.Flags().GetBool("allow-incomplete")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "allow-incomplete").Fatal("Unable to get flag")
+			}
+
+			// Get the batch-size flag:
+			batchSize, err := cmd.Flags().GetInt("batch-size")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "batch-size").Fatal("Unable to get flag")
+			}
+			if batchSize < 1 {
+				batchSize = 1
+			}
+
+			// Get the replication-factor flag:
+			replicationFactor, err := cmd.Flags().GetInt("replication-factor")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "replication-factor").Fatal("Unable to get flag")
+			}
+
+			// Get the topic flag:
+			topicName, err := cmd.Flags().GetString("topic")
+			if err != nil {
+				cli.logger.WithError(err).WithField("flag", "topic").Fatal("Unable to get flag")
+			}
+
+			// Read the manifest:
+			dir := args[0]
+			manifest, err := loadBackupManifest(dir)
+			if err != nil {
+				cli.logger.WithError(err).WithField("dir", dir).Fatal("Unable to read the backup manifest")
+			}
+			if incomplete := manifest.incompletePartitions(); len(incomplete) > 0 {
+				if !allowIncomplete {
+					cli.logger.WithField("dir", dir).WithField("partitions", incomplete).Fatal("The backup is incomplete (use --allow-incomplete to restore it anyway)")
+				}
+				cli.logger.WithField("dir", dir).WithField("partitions", incomplete).Warn("Restoring an incomplete backup")
+			}
+			if topicName == "" {
+				topicName = manifest.Topic
+			}
+			if replicationFactor < 1 {
+				replicationFactor = manifest.ReplicationFactor
+			}
+
+			// Config:
+			cli.logger.
+				WithField("sasl", cli.config.Kafka.SaslMechanism).
+				WithField("security", cli.config.Kafka.SecurityProtocol).
+				WithField("servers", cli.config.Kafka.BootstrapServers).
+				WithField("username", cli.config.Kafka.Username).
+				Debugf("Restoring topic %s from %s", topicName, dir)
+
+			// Recreate the topic (with the original partition count and configs):
+			created, err := cli.createTopic(topicName, manifest.PartitionCount, replicationFactor, manifest.Configs)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to create topic")
+			}
+			if !created {
+				cli.logger.WithField("topic", topicName).Warn("Topic already exists (restoring into it anyway)")
+			}
+
+			// Every original partition has to exist for the mapping to be preserved:
+			partitions, err := cli.topicPartitions(topicName)
+			if err != nil {
+				cli.logger.WithError(err).WithField("topic", topicName).Fatal("Unable to describe topic")
+			}
+			if len(partitions) < manifest.PartitionCount {
+				cli.logger.
+					WithField("backup_partitions", manifest.PartitionCount).
+					WithField("partitions", len(partitions)).
+					WithField("topic", topicName).
+					Fatal("The topic has fewer partitions than the backup")
+			}
+
+			// Get a producer which keeps messages on their original partitions:
+			producer, err := cli.config.Kafka.Producer(cli.logger, topicName)
+			if err != nil {
+				cli.logger.WithError(err).Fatal("Unable to prepare a Kafka producer")
+			}
+			producer.Balancer = partitionBalancer{}
+			producer.BatchSize = batchSize
+			producer.BatchTimeout = 10 * time.Millisecond
+			defer producer.Close()
+
+			// Periodically report our progress:
+			startTime := time.Now()
+			var messagesWritten int64
+			go func() {
+				for {
+					time.Sleep(time.Second)
+					cli.logger.WithField("messages", atomic.LoadInt64(&messagesWritten)).WithField("messages/s", atomic.LoadInt64(&messagesWritten)/int64(time.Since(startTime).Seconds()+1)).Info("Progress report")
+				}
+			}()
+
+			// Republish each partition in order:
+			for _, backedUp := range manifest.Partitions {
+				var batch []kafka.Message
+				publish := func() error {
+					if len(batch) == 0 {
+						return nil
+					}
+					if err := producer.WriteMessages(context.TODO(), batch...); err != nil {
+						return err
+					}
+					atomic.AddInt64(&messagesWritten, int64(len(batch)))
+					batch = batch[:0]
+					return nil
+				}
+
+				err := readSegment(filepath.Join(dir, backedUp.File), func(message kafka.Message) error {
+					message.Topic = ""
+					batch = append(batch, message)
+					if len(batch) >= batchSize {
+						return publish()
+					}
+					return nil
+				})
+				if err == nil {
+					err = publish()
+				}
+				if err != nil {
+					cli.logger.WithError(err).WithField("file", backedUp.File).WithField("partition", backedUp.Partition).Fatal("Unable to restore partition")
+				}
+				cli.logger.WithField("partition", backedUp.Partition).WithField("records", backedUp.Records).Debug("Restored partition")
+			}
+
+			cli.logger.
+				WithField("dir", dir).
+				WithField("messages", messagesWritten).
+				WithField("partitions", len(manifest.Partitions)).
+				WithField("topic", topicName).
+				Info("Restore complete")
+		},
+	}
+}
@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/configuration"
+	"github.com/segmentio/kafka-go"
+)
+
+// subscriptionOptions describe what to consume, and how:
+type subscriptionOptions struct {
+	autoCommit       bool
+	checkpoint       *checkpoint
+	groupId          string
+	offset           int64
+	partition        int
+	readerOptions    configuration.ReaderOptions
+	refreshInterval  time.Duration
+	showTransactions bool
+	topicNames       []string
+	topicRegex       *regexp.Regexp
+}
+
+// subscription consumes from a set of topics (which can grow, when they're matched by a regex):
+type subscription struct {
+	cli        *CLI
+	errors     chan error
+	messages   chan consumedMessage
+	mutex      sync.Mutex
+	newReader  func(topicName string, partition int, offset int64) (messageReader, error)
+	options    subscriptionOptions
+	partitions map[string]map[int]messageReader
+	reader     *kafka.Reader
+	topics     []string
+}
+
+// subscribe starts consuming from the given topics (and any matching the regex), with a group or directly from each partition:
+func (cli *CLI) subscribe(ctx context.Context, options subscriptionOptions) (*subscription, error) {
+	s := &subscription{
+		cli:        cli,
+		errors:     make(chan error),
+		messages:   make(chan consumedMessage),
+		options:    options,
+		partitions: make(map[string]map[int]messageReader),
+	}
+	s.newReader = s.partitionReader
+
+	// Resolve the topics for the first time:
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	// Pick up new topics (and partitions) every so often:
+	if options.topicRegex != nil || options.groupId == "" {
+		go func() {
+			ticker := time.NewTicker(options.refreshInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := s.refresh(ctx); err != nil {
+						cli.logger.WithError(err).Warn("Unable to refresh the subscribed topics")
+					}
+				}
+			}
+		}()
+	}
+
+	return s, nil
+}
+
+// ReadMessage returns the next message from any of the subscribed topics:
+func (s *subscription) ReadMessage(ctx context.Context) (consumedMessage, error) {
+	select {
+	case <-ctx.Done():
+		return consumedMessage{}, ctx.Err()
+	case err := <-s.errors:
+		return consumedMessage{}, err
+	case consumed := <-s.messages:
+		return consumed, nil
+	}
+}
+
+// Commit commits the offsets of messages to the group (if there is one):
+func (s *subscription) Commit(ctx context.Context, messages ...kafka.Message) error {
+	s.mutex.Lock()
+	reader := s.reader
+	s.mutex.Unlock()
+
+	if reader == nil || len(messages) == 0 {
+		return nil
+	}
+	return reader.CommitMessages(ctx, messages...)
+}
+
+// Close stops all of the readers:
+func (s *subscription) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.reader != nil {
+		s.reader.Close()
+	}
+	for _, readers := range s.partitions {
+		for _, reader := range readers {
+			reader.Close()
+		}
+	}
+}
+
+// refresh resolves the topics from the cluster metadata, and starts reading any new ones:
+func (s *subscription) refresh(ctx context.Context) error {
+	kafkaMetadata, err := s.cli.adminClient.Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return err
+	}
+
+	// Index the topics in the cluster:
+	clusterTopics := make(map[string]kafka.Topic, len(kafkaMetadata.Topics))
+	for _, topic := range kafkaMetadata.Topics {
+		clusterTopics[topic.Name] = topic
+	}
+
+	// Work out which topics we're subscribed to:
+	topics, missing := resolveTopics(s.options.topicNames, s.options.topicRegex, clusterTopics)
+	for _, topicName := range missing {
+		s.cli.logger.WithField("topic", topicName).Warn("Topic not found")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.options.groupId != "" {
+		return s.refreshGroup(ctx, topics)
+	}
+	return s.refreshPartitions(ctx, topics, clusterTopics)
+}
+
+// resolveTopics returns the (sorted) topics to subscribe to: named topics are always included (those missing from the cluster are returned too),
+// and other topics if they match the regex (apart from internal ones):
+func resolveTopics(topicNames []string, topicRegex *regexp.Regexp, clusterTopics map[string]kafka.Topic) ([]string, []string) {
+	resolved := make(map[string]bool)
+	var missing []string
+	for _, topicName := range topicNames {
+		if _, ok := clusterTopics[topicName]; !ok {
+			missing = append(missing, topicName)
+		}
+		resolved[topicName] = true
+	}
+	if topicRegex != nil {
+		for _, topic := range clusterTopics {
+			if !topic.Internal && topicRegex.MatchString(topic.Name) {
+				resolved[topic.Name] = true
+			}
+		}
+	}
+
+	topics := make([]string, 0, len(resolved))
+	for topicName := range resolved {
+		topics = append(topics, topicName)
+	}
+	sort.Strings(topics)
+	return topics, missing
+}
+
+// refreshGroup replaces the group reader whenever the topics change (the group then rebalances onto the new set):
+func (s *subscription) refreshGroup(ctx context.Context, topics []string) error {
+	if slices.Equal(topics, s.topics) || len(topics) == 0 {
+		return nil
+	}
+
+	reader, err := s.cli.config.Kafka.Consumer(s.cli.logger, s.options.groupId, s.options.readerOptions, topics...)
+	if err != nil {
+		return err
+	}
+	if s.reader != nil {
+		s.reader.Close()
+	}
+	s.cli.logger.WithField("group", s.options.groupId).WithField("topics", topics).Info("Subscribed to topics")
+
+	s.reader = reader
+	s.topics = topics
+	go s.read(ctx, kafkaMessageReader{Reader: reader, autoCommit: s.options.autoCommit})
+	return nil
+}
+
+// refreshPartitions starts a reader for any partitions which aren't being read yet:
+func (s *subscription) refreshPartitions(ctx context.Context, topics []string, clusterTopics map[string]kafka.Topic) error {
+	for _, topicName := range topics {
+		if s.partitions[topicName] == nil {
+			s.partitions[topicName] = make(map[int]messageReader)
+		}
+
+		for _, partition := range clusterTopics[topicName].Partitions {
+			if _, ok := s.partitions[topicName][partition.ID]; ok {
+				continue
+			}
+
+			// Only read the requested partition (from the requested offset) if we were given one:
+			offset := kafka.FirstOffset
+			if s.options.partition >= 0 {
+				if partition.ID != s.options.partition {
+					continue
+				}
+				offset = s.options.offset
+			}
+
+			// Resume from the checkpoint (if we have one):
+			if s.options.checkpoint != nil {
+				if checkpointOffset, ok := s.options.checkpoint.position(topicName, partition.ID); ok {
+					offset = checkpointOffset
+				}
+			}
+
+			reader, err := s.newReader(topicName, partition.ID, offset)
+			if err != nil {
+				return err
+			}
+			s.cli.logger.WithField("partition", partition.ID).WithField("topic", topicName).Debug("Reading partition")
+
+			s.partitions[topicName][partition.ID] = reader
+			go s.read(ctx, reader)
+		}
+	}
+
+	s.topics = topics
+	return nil
+}
+
+// partitionReader returns a reader for one partition (which can see transaction details if we were asked to show them):
+func (s *subscription) partitionReader(topicName string, partition int, offset int64) (messageReader, error) {
+	if s.options.showTransactions {
+		return s.cli.newTransactionReader(topicName, partition, offset)
+	}
+
+	reader, err := s.cli.config.Kafka.PartitionReader(s.cli.logger, topicName, partition, offset, s.options.readerOptions)
+	if err != nil {
+		return nil, err
+	}
+	return kafkaMessageReader{Reader: reader}, nil
+}
+
+// read passes messages (and errors) from a reader on until it is closed:
+func (s *subscription) read(ctx context.Context, reader messageReader) {
+	for {
+		consumed, err := reader.read(ctx)
+		switch {
+		case errors.Is(err, io.EOF) || ctx.Err() != nil:
+			return
+		case err != nil:
+			select {
+			case s.errors <- err:
+			case <-ctx.Done():
+			}
+		default:
+			select {
+			case s.messages <- consumed:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// idleReader is a messageReader which never has any messages:
+type idleReader struct{}
+
+func (idleReader) Close() error { return nil }
+
+func (idleReader) read(ctx context.Context) (consumedMessage, error) {
+	<-ctx.Done()
+	return consumedMessage{}, ctx.Err()
+}
+
+// testClusterTopics makes cluster metadata with some topics (each with a number of partitions):
+func testClusterTopics(partitionCounts map[string]int, internal ...string) map[string]kafka.Topic {
+	clusterTopics := make(map[string]kafka.Topic)
+	for topicName, partitionCount := range partitionCounts {
+		topic := kafka.Topic{Name: topicName}
+		for partition := 0; partition < partitionCount; partition++ {
+			topic.Partitions = append(topic.Partitions, kafka.Partition{ID: partition, Topic: topicName})
+		}
+		clusterTopics[topicName] = topic
+	}
+	for _, topicName := range internal {
+		clusterTopics[topicName] = kafka.Topic{Internal: true, Name: topicName}
+	}
+	return clusterTopics
+}
+
+func TestResolveTopics(t *testing.T) {
+	clusterTopics := testClusterTopics(map[string]int{"orders.eu": 1, "orders.us": 1, "payments": 1}, "__consumer_offsets")
+
+	// Named topics are always included (and reported if they're missing):
+	topics, missing := resolveTopics([]string{"payments", "refunds"}, nil, clusterTopics)
+	assert.Equal(t, []string{"payments", "refunds"}, topics)
+	assert.Equal(t, []string{"refunds"}, missing)
+
+	// Regexes match topics in the cluster (but not internal ones), alongside named topics:
+	topics, missing = resolveTopics([]string{"payments"}, regexp.MustCompile(`^orders\.|^__`), clusterTopics)
+	assert.Equal(t, []string{"orders.eu", "orders.us", "payments"}, topics)
+	assert.Empty(t, missing)
+}
+
+func TestSubscriptionRefreshPartitions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	newSubscription := func(options subscriptionOptions) (*subscription, *[]string) {
+		var started []string
+		s := &subscription{
+			cli:        &CLI{logger: logger},
+			errors:     make(chan error),
+			messages:   make(chan consumedMessage),
+			options:    options,
+			partitions: make(map[string]map[int]messageReader),
+		}
+		s.newReader = func(topicName string, partition int, offset int64) (messageReader, error) {
+			started = append(started, fmt.Sprintf("%s/%d@%d", topicName, partition, offset))
+			return idleReader{}, nil
+		}
+		return s, &started
+	}
+
+	// Every partition is read from the start, and refreshing only starts readers for new topics and partitions:
+	s, started := newSubscription(subscriptionOptions{partition: -1})
+	assert.NoError(t, s.refreshPartitions(ctx, []string{"orders.eu"}, testClusterTopics(map[string]int{"orders.eu": 2})))
+	assert.Equal(t, []string{"orders.eu/0@-2", "orders.eu/1@-2"}, *started)
+	assert.NoError(t, s.refreshPartitions(ctx, []string{"orders.eu", "orders.us"}, testClusterTopics(map[string]int{"orders.eu": 3, "orders.us": 1})))
+	assert.Equal(t, []string{"orders.eu/0@-2", "orders.eu/1@-2", "orders.eu/2@-2", "orders.us/0@-2"}, *started)
+	assert.Equal(t, []string{"orders.eu", "orders.us"}, s.topics)
+
+	// A partition is read from the requested offset (other partitions are left alone):
+	s, started = newSubscription(subscriptionOptions{offset: 1000, partition: 1})
+	assert.NoError(t, s.refreshPartitions(ctx, []string{"orders.eu"}, testClusterTopics(map[string]int{"orders.eu": 3})))
+	assert.Equal(t, []string{"orders.eu/1@1000"}, *started)
+
+	// Checkpoints are resumed from (where they have a position):
+	testCheckpoint, err := loadCheckpoint(filepath.Join(t.TempDir(), "orders.checkpoint"))
+	assert.NoError(t, err, "Error while loading a missing checkpoint")
+	testCheckpoint.advance(kafka.Message{Topic: "orders.eu", Partition: 1, Offset: 41})
+	s, started = newSubscription(subscriptionOptions{checkpoint: testCheckpoint, partition: -1})
+	assert.NoError(t, s.refreshPartitions(ctx, []string{"orders.eu"}, testClusterTopics(map[string]int{"orders.eu": 2})))
+	assert.Equal(t, []string{"orders.eu/0@-2", "orders.eu/1@42"}, *started)
+}
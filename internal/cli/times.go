@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the absolute time formats we accept on the command line:
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTime understands RFC3339 (and shorter) timestamps, unix milliseconds, "now", and durations ago (eg "90m" or "-2h"):
+func parseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	// Now:
+	if value == "now" {
+		return now, nil
+	}
+
+	// Unix milliseconds:
+	if milliseconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(milliseconds), nil
+	}
+
+	// Durations ago:
+	if duration, err := time.ParseDuration(strings.TrimPrefix(value, "-")); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	// Absolute times (without a zone are UTC):
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse time %q (use RFC3339, unix milliseconds, \"now\" or a duration ago such as 2h)", value)
+}
+
+// timeFlag parses an optional time flag (returning the zero time if it wasn't given):
+func timeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return parseTime(value, time.Now())
+}
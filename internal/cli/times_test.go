@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, expected := range map[string]time.Time{
+		"now":                       now,
+		"2h":                        now.Add(-2 * time.Hour),
+		"-90m":                      now.Add(-90 * time.Minute),
+		"1704164645000":             time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02T03:04:05Z":      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02T03:04:05+01:00": time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC),
+		"2024-01-02 03:04:05":       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02":                time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		parsed, err := parseTime(value, now)
+		assert.NoError(t, err, "Error while parsing %s", value)
+		assert.True(t, expected.Equal(parsed), "%s parsed as %s", value, parsed)
+	}
+
+	_, err := parseTime("yesterday-ish", now)
+	assert.Error(t, err, "Expected an unparseable time to be refused")
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// topicConfig retrieves (and caches) the effective config of a topic.
+// Failures are cached too (as an empty config, with a warning the first time), so a topic we can't describe doesn't cost a round trip per message:
+func (cli *CLI) topicConfig(topicName string) (map[string]string, error) {
+	cli.topicConfigsMutex.Lock()
+	defer cli.topicConfigsMutex.Unlock()
+
+	// Check the cache first:
+	if topicConfig, ok := cli.topicConfigs[topicName]; ok {
+		return topicConfig, nil
+	}
+
+	topicConfig, err := cli.describeTopicConfig(topicName)
+	if err != nil {
+		cli.logger.WithError(err).WithField("topic", topicName).Warn("Unable to retrieve the topic config (carrying on without it)")
+		cli.topicConfigs[topicName] = map[string]string{}
+		return nil, err
+	}
+
+	cli.topicConfigs[topicName] = topicConfig
+	return topicConfig, nil
+}
+
+// describeTopicConfig retrieves the effective config of a topic:
+func (cli *CLI) describeTopicConfig(topicName string) (map[string]string, error) {
+	configEntries, err := cli.topicConfigEntries(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flatten it:
+	topicConfig := make(map[string]string, len(configEntries))
+	for _, configEntry := range configEntries {
+		topicConfig[configEntry.ConfigName] = configEntry.ConfigValue
+	}
+
+	return topicConfig, nil
+}
+
+// topicConfigEntries describes every config of a topic (with where each one came from):
+func (cli *CLI) topicConfigEntries(topicName string) ([]kafka.DescribeConfigResponseConfigEntry, error) {
+	response, err := cli.adminClient.DescribeConfigs(
+		context.TODO(),
+		&kafka.DescribeConfigsRequest{
+			Resources: []kafka.DescribeConfigRequestResource{
+				{
+					ResourceName: topicName,
+					ResourceType: kafka.ResourceTypeTopic,
+				},
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var configEntries []kafka.DescribeConfigResponseConfigEntry
+	for _, resource := range response.Resources {
+		if resource.Error != nil {
+			return nil, fmt.Errorf("unable to describe topic %s: %w", topicName, resource.Error)
+		}
+		configEntries = append(configEntries, resource.ConfigEntries...)
+	}
+
+	return configEntries, nil
+}
+
+// timestampType returns the type of timestamp a topic records (or blank if it can't be determined):
+func (cli *CLI) timestampType(topicName string) string {
+	topicConfig, err := cli.topicConfig(topicName)
+	if err != nil {
+		return ""
+	}
+	return topicConfig["message.timestamp.type"]
+}
+
+// Where a config value came from (in DescribeConfigs responses):
+const configSourceDynamicTopic = 1
+
+// topicConfigOverrides returns the configs which have been set on a topic (as opposed to inherited from the broker):
+func (cli *CLI) topicConfigOverrides(topicName string) (map[string]string, error) {
+	configEntries, err := cli.topicConfigEntries(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]string)
+	for _, configEntry := range configEntries {
+		if configEntry.ConfigSource != configSourceDynamicTopic || configEntry.ReadOnly || configEntry.IsSensitive {
+			continue
+		}
+		overrides[configEntry.ConfigName] = configEntry.ConfigValue
+	}
+
+	return overrides, nil
+}
+
+// topicConfigValues retrieves one config of many topics at once (topics which don't report it are left out).
+// Topics which can't be described (eg because of an ACL) get an error of their own, rather than failing the rest:
+func (cli *CLI) topicConfigValues(topicNames []string, configName string) (map[string]string, map[string]error, error) {
+	request := &kafka.DescribeConfigsRequest{}
+	for _, topicName := range topicNames {
+		request.Resources = append(request.Resources, kafka.DescribeConfigRequestResource{
+			ConfigNames:  []string{configName},
+			ResourceName: topicName,
+			ResourceType: kafka.ResourceTypeTopic,
+		})
+	}
+	if len(request.Resources) == 0 {
+		return nil, nil, nil
+	}
+
+	response, err := cli.adminClient.DescribeConfigs(context.TODO(), request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string, len(topicNames))
+	topicErrors := make(map[string]error)
+	for _, resource := range response.Resources {
+		if resource.Error != nil {
+			topicErrors[resource.ResourceName] = resource.Error
+			continue
+		}
+		for _, configEntry := range resource.ConfigEntries {
+			if configEntry.ConfigName == configName {
+				values[resource.ResourceName] = configEntry.ConfigValue
+			}
+		}
+	}
+
+	return values, topicErrors, nil
+}
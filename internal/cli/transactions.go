@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/chrusty/kafka-cli/internal/envelope"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+)
+
+// Control record types (the second half of a control record's key):
+const (
+	controlTypeAbort  = 0
+	controlTypeCommit = 1
+)
+
+// Isolation levels which can be chosen with --isolation:
+const (
+	isolationReadCommitted   = "read_committed"
+	isolationReadUncommitted = "read_uncommitted"
+)
+
+// Fetch sizes for transactionReader:
+const (
+	transactionFetchMaxBytes          = 16 * 1024 * 1024
+	transactionFetchMaxWait           = 1000
+	transactionFetchPartitionMaxBytes = 1024 * 1024
+)
+
+// consumedMessage is a message along with what we know about its transaction (if anything):
+type consumedMessage struct {
+	message     kafka.Message
+	transaction *envelope.Transaction
+}
+
+// messageReader reads consumed messages from a partition (or a group):
+type messageReader interface {
+	Close() error
+	read(ctx context.Context) (consumedMessage, error)
+}
+
+// kafkaMessageReader adapts a kafka.Reader (which hides transaction details):
+type kafkaMessageReader struct {
+	*kafka.Reader
+	autoCommit bool
+}
+
+func (kmr kafkaMessageReader) read(ctx context.Context) (consumedMessage, error) {
+
+	// Group readers only commit by themselves when reading (as opposed to fetching):
+	if kmr.autoCommit {
+		message, err := kmr.ReadMessage(ctx)
+		return consumedMessage{message: message}, err
+	}
+
+	message, err := kmr.FetchMessage(ctx)
+	return consumedMessage{message: message}, err
+}
+
+// transactionReader reads a partition with raw fetch requests (uncommitted), so it can see control records and aborted transactions:
+type transactionReader struct {
+	abortedProducers    map[int64]bool
+	abortedTransactions []fetch.ResponseTransaction
+	cli                 *CLI
+	closed              chan struct{}
+	closeOnce           sync.Once
+	offset              int64
+	partition           int
+	pending             []consumedMessage
+	topicName           string
+}
+
+// newTransactionReader returns a transactionReader starting at the given offset (or kafka.FirstOffset / kafka.LastOffset):
+func (cli *CLI) newTransactionReader(topicName string, partition int, offset int64) (*transactionReader, error) {
+
+	// Resolve the special offsets:
+	if offset < 0 {
+		offsets, err := cli.listOffsets(topicName, []int{partition}, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = offsets[partition]
+	}
+
+	return &transactionReader{
+		abortedProducers: make(map[int64]bool),
+		cli:              cli,
+		closed:           make(chan struct{}),
+		offset:           offset,
+		partition:        partition,
+		topicName:        topicName,
+	}, nil
+}
+
+// Close stops the reader:
+func (tr *transactionReader) Close() error {
+	tr.closeOnce.Do(func() { close(tr.closed) })
+	return nil
+}
+
+// read returns the next record (fetching more when we run out):
+func (tr *transactionReader) read(ctx context.Context) (consumedMessage, error) {
+	for len(tr.pending) == 0 {
+		select {
+		case <-tr.closed:
+			return consumedMessage{}, io.EOF
+		default:
+		}
+		if err := tr.fetch(ctx); err != nil {
+			return consumedMessage{}, err
+		}
+	}
+
+	next := tr.pending[0]
+	tr.pending = tr.pending[1:]
+	return next, nil
+}
+
+// fetch retrieves the next batch of records (along with the aborted transactions which overlap them):
+func (tr *transactionReader) fetch(ctx context.Context) error {
+	response, err := tr.cli.adminClient.Transport.RoundTrip(ctx, tr.cli.adminClient.Addr, &fetch.Request{
+		ReplicaID:      -1,
+		MaxWaitTime:    transactionFetchMaxWait,
+		MinBytes:       1,
+		MaxBytes:       transactionFetchMaxBytes,
+		IsolationLevel: int8(kafka.ReadUncommitted),
+		SessionEpoch:   -1,
+		Topics: []fetch.RequestTopic{
+			{
+				Topic: tr.topicName,
+				Partitions: []fetch.RequestPartition{
+					{
+						Partition:          int32(tr.partition),
+						CurrentLeaderEpoch: -1,
+						FetchOffset:        tr.offset,
+						LogStartOffset:     -1,
+						PartitionMaxBytes:  transactionFetchPartitionMaxBytes,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, topic := range response.(*fetch.Response).Topics {
+		for _, partition := range topic.Partitions {
+			if partition.ErrorCode != 0 {
+				return kafka.Error(partition.ErrorCode)
+			}
+
+			// The broker reports every aborted transaction which overlaps this fetch:
+			tr.abortedTransactions = append([]fetch.ResponseTransaction(nil), partition.AbortedTransactions...)
+			sort.Slice(tr.abortedTransactions, func(i, j int) bool {
+				return tr.abortedTransactions[i].FirstOffset < tr.abortedTransactions[j].FirstOffset
+			})
+			if err := tr.readRecordSet(partition.RecordSet); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readRecordSet works through the batches in a record set, tracking which producers are in aborted transactions:
+func (tr *transactionReader) readRecordSet(recordSet protocol.RecordSet) error {
+	if recordSet.Records == nil {
+		return nil
+	}
+
+	stream, ok := recordSet.Records.(*protocol.RecordStream)
+	if !ok {
+		stream = &protocol.RecordStream{Records: []protocol.RecordReader{recordSet.Records}}
+	}
+
+	for _, batch := range stream.Records {
+		var producerID int64 = -1
+		var transactional, control bool
+		switch batch := batch.(type) {
+		case *protocol.RecordBatch:
+			producerID = batch.ProducerID
+			transactional = batch.Attributes.Transactional()
+		case *protocol.ControlBatch:
+			producerID = batch.ProducerID
+			transactional = true
+			control = true
+		}
+
+		for {
+			record, err := batch.ReadRecord()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			consumed, err := tr.consume(record, producerID, transactional, control)
+			if err != nil {
+				return err
+			}
+			if consumed != nil {
+				tr.pending = append(tr.pending, *consumed)
+			}
+		}
+	}
+
+	return nil
+}
+
+// consume turns a record into a message (skipping any before our offset), annotated with its transaction:
+func (tr *transactionReader) consume(record *protocol.Record, producerID int64, transactional, control bool) (*consumedMessage, error) {
+	if record.Key != nil {
+		defer record.Key.Close()
+	}
+	if record.Value != nil {
+		defer record.Value.Close()
+	}
+	key, err := protocol.ReadAll(record.Key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := protocol.ReadAll(record.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Batches can start before the offset we asked for:
+	if record.Offset < tr.offset {
+		return nil, nil
+	}
+	tr.offset = record.Offset + 1
+
+	consumed := &consumedMessage{
+		message: kafka.Message{
+			Headers:   append([]kafka.Header(nil), record.Headers...),
+			Key:       key,
+			Offset:    record.Offset,
+			Partition: tr.partition,
+			Time:      record.Time,
+			Topic:     tr.topicName,
+			Value:     value,
+		},
+	}
+	if !transactional {
+		return consumed, nil
+	}
+
+	// A producer's transaction is aborted from the first offset the broker reports for it, until its abort marker:
+	for len(tr.abortedTransactions) > 0 && tr.abortedTransactions[0].FirstOffset <= record.Offset {
+		tr.abortedProducers[tr.abortedTransactions[0].ProducerID] = true
+		tr.abortedTransactions = tr.abortedTransactions[1:]
+	}
+
+	consumed.transaction = &envelope.Transaction{
+		Aborted:    tr.abortedProducers[producerID],
+		ProducerID: producerID,
+	}
+
+	// Control records (transaction markers) have the control type in their key:
+	if control && len(key) == 4 {
+		switch int16(key[2])<<8 | int16(key[3]) {
+		case controlTypeAbort:
+			consumed.transaction.Control = envelope.ControlAbort
+			delete(tr.abortedProducers, producerID)
+		case controlTypeCommit:
+			consumed.transaction.Control = envelope.ControlCommit
+		}
+	}
+
+	return consumed, nil
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/chrusty/kafka-cli/internal/envelope"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionReader(t *testing.T) {
+	reader := &transactionReader{
+		abortedProducers: make(map[int64]bool),
+		offset:           1,
+		partition:        3,
+		topicName:        "payments",
+		abortedTransactions: []fetch.ResponseTransaction{
+			{ProducerID: 7, FirstOffset: 2},
+		},
+	}
+
+	// consume wraps a record (control records carry their type in the key):
+	consume := func(offset int64, data []byte, producerID int64, transactional, control bool) *consumedMessage {
+		record := &protocol.Record{Offset: offset, Value: protocol.NewBytes(data)}
+		if control {
+			record = &protocol.Record{Offset: offset, Key: protocol.NewBytes(data)}
+		}
+		consumed, err := reader.consume(record, producerID, transactional, control)
+		assert.NoError(t, err, "Error while consuming offset %d", offset)
+		return consumed
+	}
+
+	// Records before our offset are skipped:
+	assert.Nil(t, consume(0, []byte("old"), -1, false, false))
+
+	// Plain records have no transaction:
+	plain := consume(1, []byte("plain"), -1, false, false)
+	assert.Equal(t, "payments", plain.message.Topic)
+	assert.Equal(t, 3, plain.message.Partition)
+	assert.Nil(t, plain.transaction)
+
+	// Producer 7's records are aborted from the first offset the broker reported, up to its abort marker:
+	assert.Equal(t, &envelope.Transaction{Aborted: true, ProducerID: 7}, consume(2, []byte("a"), 7, true, false).transaction)
+	assert.Equal(t, &envelope.Transaction{Aborted: false, ProducerID: 8}, consume(3, []byte("b"), 8, true, false).transaction)
+	assert.Equal(t, &envelope.Transaction{Aborted: true, Control: envelope.ControlAbort, ProducerID: 7}, consume(4, []byte{0, 0, 0, controlTypeAbort}, 7, true, true).transaction)
+	assert.Equal(t, &envelope.Transaction{Aborted: false, ProducerID: 7}, consume(5, []byte("c"), 7, true, false).transaction)
+	assert.Equal(t, &envelope.Transaction{Aborted: false, Control: envelope.ControlCommit, ProducerID: 8}, consume(6, []byte{0, 0, 0, controlTypeCommit}, 8, true, true).transaction)
+	assert.Equal(t, int64(7), reader.offset)
+}
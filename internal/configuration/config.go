@@ -9,8 +9,9 @@ import (
 
 // Config for as many generic deps as we can handle:
 type Config struct {
-	Kafka   KafkaConfig
-	Logging LoggingConfig
+	Kafka          KafkaConfig
+	Logging        LoggingConfig
+	SchemaRegistry SchemaRegistryConfig
 }
 
 // Load prepares a new config and populates it from environment variables:
@@ -21,6 +22,7 @@ func Load(logger *logrus.Logger) (*Config, error) {
 		newConfig,
 		&newConfig.Kafka,
 		&newConfig.Logging,
+		&newConfig.SchemaRegistry,
 	} {
 		if err := env.Parse(configSection); err != nil {
 			return nil, fmt.Errorf("unable to load the config: %v", err)
@@ -1,7 +1,11 @@
 package configuration
 
 import (
+	"crypto/tls"
+	"fmt"
+
 	"github.com/chrusty/kafka-cli/internal/types"
+	"github.com/segmentio/kafka-go/sasl"
 	"github.com/segmentio/kafka-go/sasl/scram"
 	"github.com/sirupsen/logrus"
 )
@@ -10,10 +14,14 @@ var defaultSaslAlgorithm = scram.SHA512
 
 // KafkaConfig configures the Kafka client:
 type KafkaConfig struct {
+	AddressMap       []string `env:"KAFKA_ADDRESSMAP"` // Broker address rewrites [advertised=local] (eg "b-1.internal:9092=localhost:19092")
 	AWSRegion        string   `env:"KAFKA_AWSREGION" envDefault:"ap-southeast-2"`
 	BootstrapServers []string `env:"KAFKA_BOOTSTRAPSERVERS" envDefault:"localhost:9092"`
 	IAMAuth          bool     `env:"KAFKA_IAMAUTH" envDefault:"false"`               // Set this to true to enable IAM auth with SASL/SCRAM
 	Password         string   `env:"KAFKA_PASSWORD"`                                 // SASL/SCRAM password
+	ProxyPassword    string   `env:"KAFKA_PROXYPASSWORD"`                            // Proxy password (overrides any in the proxy URL)
+	ProxyURL         string   `env:"KAFKA_PROXYURL"`                                 // Proxy for broker connections [socks5://host:port, http://host:port]
+	ProxyUsername    string   `env:"KAFKA_PROXYUSERNAME"`                            // Proxy username (overrides any in the proxy URL)
 	RequiredAcks     int      `env:"KAFKA_REQUIREDACKS" envDefault:"1"`              // Required ACKS [1,2]
 	SaslMechanism    string   `env:"KAFKA_SASLMECHANISM" envDefault:"SCRAM-SHA-512"` // [SCRAM-SHA-256, SCRAM-SHA-512]
 	SecurityProtocol string   `env:"KAFKA_SECURITYPROTOCOL" envDefault:"PLAINTEXT"`  // [AWS_MSK_IAM, SASL_SSL, SASL_PLAINTEXT, SSL, PLAINTEXT]
@@ -51,3 +59,53 @@ func (kc *KafkaConfig) saslAlgorithm(logger *logrus.Logger) scram.Algorithm {
 		return defaultSaslAlgorithm
 	}
 }
+
+// Security returns the SASL mechanism and TLS config (either may be nil) for our security protocol:
+func (kc *KafkaConfig) Security(logger *logrus.Logger) (sasl.Mechanism, *tls.Config, error) {
+	switch kc.SecurityProtocol {
+
+	case types.SecProtocolPlaintext:
+		return nil, nil, nil
+
+	case types.SecProtocolAWSMSKIAM:
+
+		// Get an AWS-loaded SASL mechanism:
+		saslMechanism, err := AWSSaslMechanismV1()
+		if err != nil {
+			return nil, nil, err
+		}
+		return saslMechanism, &tls.Config{}, nil
+
+	case types.SecProtocolSSL:
+		return nil, &tls.Config{}, nil
+
+	case types.SecProtocolSaslPlaintext:
+
+		// Define an SASL mechanism:
+		saslMechanism, err := scram.Mechanism(
+			kc.saslAlgorithm(logger),
+			kc.Username,
+			kc.Password,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return saslMechanism, nil, nil
+
+	case types.SecProtocolSaslSSL:
+
+		// Define an SASL mechanism:
+		saslMechanism, err := scram.Mechanism(
+			kc.saslAlgorithm(logger),
+			kc.Username,
+			kc.Password,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return saslMechanism, &tls.Config{}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported security protocol %s", kc.SecurityProtocol)
+	}
+}
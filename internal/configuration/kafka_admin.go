@@ -1,87 +1,46 @@
 package configuration
 
 import (
-	"crypto/tls"
-	"fmt"
-
-	"github.com/chrusty/kafka-cli/internal/types"
 	"github.com/segmentio/kafka-go"
-	"github.com/segmentio/kafka-go/sasl/scram"
 	"github.com/sirupsen/logrus"
 )
 
 // Admin returns a Kafka Client based on our config:
 func (kc *KafkaConfig) Admin(logger *logrus.Logger) (*kafka.Client, error) {
 
+	// Prepare a transport (with our custom auth settings):
+	transport, err := kc.Transport(logger)
+	if err != nil {
+		return nil, err
+	}
+
 	// Prepare a low-level client:
 	client := &kafka.Client{
-		Addr: kafka.TCP(kc.BootstrapServers...),
+		Addr:      kafka.TCP(kc.BootstrapServers...),
+		Transport: transport,
 	}
 
-	switch kc.SecurityProtocol {
-
-	case types.SecProtocolPlaintext:
-
-		client.Transport = &kafka.Transport{}
-
-	case types.SecProtocolAWSMSKIAM:
-
-		// Get an AWS-loaded SASL mechanism:
-		saslMechanism, err := AWSSaslMechanismV1()
-		if err != nil {
-			return nil, err
-		}
-
-		// Transport:
-		client.Transport = &kafka.Transport{
-			SASL: saslMechanism,
-			TLS:  &tls.Config{},
-		}
-
-	case types.SecProtocolSSL:
-
-		client.Transport = &kafka.Transport{
-			TLS: &tls.Config{},
-		}
-
-	case types.SecProtocolSaslPlaintext:
-
-		// Define an SASL mechanism:
-		saslMechanism, err := scram.Mechanism(
-			kc.saslAlgorithm(logger),
-			kc.Username,
-			kc.Password,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Transport:
-		client.Transport = &kafka.Transport{
-			SASL: saslMechanism,
-		}
-
-	case types.SecProtocolSaslSSL:
+	return client, nil
+}
 
-		// Define an SASL mechanism:
-		saslMechanism, err := scram.Mechanism(
-			kc.saslAlgorithm(logger),
-			kc.Username,
-			kc.Password,
-		)
-		if err != nil {
-			return nil, err
-		}
+// Transport returns a Kafka Transport (with our custom auth settings):
+func (kc *KafkaConfig) Transport(logger *logrus.Logger) (*kafka.Transport, error) {
 
-		// Transport:
-		client.Transport = &kafka.Transport{
-			SASL: saslMechanism,
-			TLS:  &tls.Config{},
-		}
+	// Get the SASL mechanism and TLS config for our security protocol:
+	saslMechanism, tlsConfig, err := kc.Security(logger)
+	if err != nil {
+		return nil, err
+	}
 
-	default:
-		return nil, fmt.Errorf("unsupported security protocol %s", kc.SecurityProtocol)
+	// Get a dial function (which applies our address map):
+	dialFunc, err := kc.DialFunc()
+	if err != nil {
+		return nil, err
 	}
 
-	return client, nil
+	return &kafka.Transport{
+		Dial: dialFunc,
+		SASL: saslMechanism,
+		TLS:  tlsConfig,
+	}, nil
 }
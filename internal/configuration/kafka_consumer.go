@@ -1,26 +1,29 @@
 package configuration
 
 import (
-	"crypto/tls"
 	"fmt"
 	"time"
 
-	"github.com/chrusty/kafka-cli/internal/types"
 	"github.com/segmentio/kafka-go"
-	"github.com/segmentio/kafka-go/sasl/scram"
 	"github.com/sirupsen/logrus"
 )
 
-// Consumer returns a Kafka Consumer based on our config:
-func (kc *KafkaConfig) Consumer(logger *logrus.Logger, groupId, topicName string) (*kafka.Reader, error) {
+// ReaderOptions tune how readers fetch messages:
+type ReaderOptions struct {
+	CommitInterval time.Duration        // How often group offsets are committed (0 commits synchronously)
+	IsolationLevel kafka.IsolationLevel // Whether messages from aborted (or still open) transactions are read
+}
+
+// Consumer returns a Kafka Consumer based on our config (several topics can only be consumed with a group):
+func (kc *KafkaConfig) Consumer(logger *logrus.Logger, groupId string, options ReaderOptions, topicNames ...string) (*kafka.Reader, error) {
 
 	// Prepare a reader config:
 	readerConfig := kafka.ReaderConfig{
 		Brokers:        kc.BootstrapServers,
-		CommitInterval: 5 * time.Second,
+		CommitInterval: options.CommitInterval,
 		ErrorLogger:    &kafkaErrorLogger{logger: logger},
+		IsolationLevel: options.IsolationLevel,
 		Logger:         &kafkaLogger{logger: logger},
-		Topic:          topicName,
 	}
 
 	// Add the groupId if one was provided:
@@ -28,70 +31,78 @@ func (kc *KafkaConfig) Consumer(logger *logrus.Logger, groupId, topicName string
 		readerConfig.GroupID = groupId
 	}
 
-	// Prepare a dialer (with our custom auth settings):
-	dialer := &kafka.Dialer{
-		ClientID: "kafka-cli",
-		Timeout:  10 * time.Second,
+	// Groups can subscribe to several topics:
+	switch {
+	case len(topicNames) == 1:
+		readerConfig.Topic = topicNames[0]
+	case groupId != "":
+		readerConfig.GroupTopics = topicNames
+	default:
+		return nil, fmt.Errorf("consuming %d topics requires a consumer group", len(topicNames))
 	}
 
-	switch kc.SecurityProtocol {
-
-	case types.SecProtocolPlaintext:
-
-	case types.SecProtocolAWSMSKIAM:
-
-		// Get an AWS-loaded SASL mechanism:
-		saslMechanism, err := AWSSaslMechanismV1()
-		if err != nil {
-			return nil, err
-		}
-
-		// Add it to our dialer:
-		dialer.SASLMechanism = saslMechanism
-		dialer.TLS = &tls.Config{}
+	// Prepare a dialer (with our custom auth settings):
+	dialer, err := kc.Dialer(logger)
+	if err != nil {
+		return nil, err
+	}
 
-	case types.SecProtocolSSL:
+	// Put a reader together with our config:
+	readerConfig.Dialer = dialer
+	reader := kafka.NewReader(readerConfig)
+	return reader, nil
+}
 
-		// Configure our dialer to use TLS:
-		dialer.TLS = &tls.Config{}
+// PartitionReader returns a Kafka reader for a single partition (without a consumer group), starting at the given offset:
+func (kc *KafkaConfig) PartitionReader(logger *logrus.Logger, topicName string, partition int, offset int64, options ReaderOptions) (*kafka.Reader, error) {
 
-	case types.SecProtocolSaslPlaintext:
+	// Prepare a dialer (with our custom auth settings):
+	dialer, err := kc.Dialer(logger)
+	if err != nil {
+		return nil, err
+	}
 
-		// Define an SASL mechanism:
-		saslMechanism, err := scram.Mechanism(
-			kc.saslAlgorithm(logger),
-			kc.Username,
-			kc.Password,
-		)
-		if err != nil {
-			return nil, err
-		}
+	// Put a reader together with our config:
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        kc.BootstrapServers,
+		Dialer:         dialer,
+		ErrorLogger:    &kafkaErrorLogger{logger: logger},
+		IsolationLevel: options.IsolationLevel,
+		Logger:         &kafkaLogger{logger: logger},
+		MaxWait:        time.Second,
+		Partition:      partition,
+		Topic:          topicName,
+	})
 
-		// Add it to our dialer:
-		dialer.SASLMechanism = saslMechanism
+	// Start at the requested offset:
+	if err := reader.SetOffset(offset); err != nil {
+		reader.Close()
+		return nil, err
+	}
 
-	case types.SecProtocolSaslSSL:
+	return reader, nil
+}
 
-		// Define an SASL mechanism:
-		saslMechanism, err := scram.Mechanism(
-			kc.saslAlgorithm(logger),
-			kc.Username,
-			kc.Password,
-		)
-		if err != nil {
-			return nil, err
-		}
+// Dialer returns a Kafka Dialer (with our custom auth settings):
+func (kc *KafkaConfig) Dialer(logger *logrus.Logger) (*kafka.Dialer, error) {
 
-		// Configure our dialer to use SCRAM and TLS:
-		dialer.SASLMechanism = saslMechanism
-		dialer.TLS = &tls.Config{}
+	// Get the SASL mechanism and TLS config for our security protocol:
+	saslMechanism, tlsConfig, err := kc.Security(logger)
+	if err != nil {
+		return nil, err
+	}
 
-	default:
-		return nil, fmt.Errorf("unsupported security protocol %s", kc.SecurityProtocol)
+	// Get a dial function (which applies our address map):
+	dialFunc, err := kc.DialFunc()
+	if err != nil {
+		return nil, err
 	}
 
-	// Put a reader together with our config:
-	readerConfig.Dialer = dialer
-	reader := kafka.NewReader(readerConfig)
-	return reader, nil
+	return &kafka.Dialer{
+		ClientID:      "kafka-cli",
+		DialFunc:      dialFunc,
+		SASLMechanism: saslMechanism,
+		Timeout:       10 * time.Second,
+		TLS:           tlsConfig,
+	}, nil
 }
@@ -0,0 +1,84 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Context names have to make a valid env-var prefix:
+var contextNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// KafkaContext loads the Kafka config for a named context (eg "prod" reads PROD_KAFKA_BOOTSTRAPSERVERS, PROD_KAFKA_USERNAME etc):
+func KafkaContext(name string) (*KafkaConfig, error) {
+	if name == "" {
+		return nil, fmt.Errorf("a context name is required")
+	}
+	if !contextNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid context name %s (only letters, digits and underscores are allowed, starting with a letter)", name)
+	}
+	prefix := strings.ToUpper(name) + "_"
+
+	kafkaConfig := &KafkaConfig{}
+	configValue := reflect.ValueOf(kafkaConfig).Elem()
+	configType := configValue.Type()
+
+	// Each field comes from its prefixed env-var, falling back to the usual default (never to the un-prefixed env-var).
+	// Bootstrap servers have no fallback, so a context which isn't configured can't quietly point at localhost:
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		envName := field.Tag.Get("env")
+		if envName == "" {
+			continue
+		}
+
+		value, ok := os.LookupEnv(prefix + envName)
+		if !ok && field.Name != "BootstrapServers" {
+			value, ok = field.Tag.Lookup("envDefault")
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setField(configValue.Field(i), value); err != nil {
+			return nil, fmt.Errorf("invalid value for %s%s: %w", prefix, envName, err)
+		}
+	}
+
+	if len(kafkaConfig.BootstrapServers) == 0 {
+		return nil, fmt.Errorf("no bootstrap servers for context %s (set %sKAFKA_BOOTSTRAPSERVERS)", name, prefix)
+	}
+
+	return kafkaConfig, nil
+}
+
+// setField parses an env-var value into a config field:
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Slice:
+		if value == "" {
+			return nil
+		}
+		field.Set(reflect.ValueOf(strings.Split(value, ",")))
+	case reflect.String:
+		field.SetString(value)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaContext(t *testing.T) {
+	t.Setenv("KAFKA_USERNAME", "default-user")
+	t.Setenv("PROD_KAFKA_BOOTSTRAPSERVERS", "b-1.prod:9096,b-2.prod:9096")
+	t.Setenv("PROD_KAFKA_REQUIREDACKS", "-1")
+	t.Setenv("PROD_KAFKA_SECURITYPROTOCOL", "SASL_SSL")
+
+	// Prefixed env-vars are used, and anything else gets the usual default (not the default context's value):
+	kafkaConfig, err := KafkaContext("prod")
+	assert.NoError(t, err, "Error while loading a context")
+	assert.Equal(t, []string{"b-1.prod:9096", "b-2.prod:9096"}, kafkaConfig.BootstrapServers)
+	assert.Equal(t, -1, kafkaConfig.RequiredAcks)
+	assert.Equal(t, "SASL_SSL", kafkaConfig.SecurityProtocol)
+	assert.Equal(t, "SCRAM-SHA-512", kafkaConfig.SaslMechanism)
+	assert.Empty(t, kafkaConfig.Username)
+
+	// Contexts without bootstrap servers aren't given the default ones:
+	_, err = KafkaContext("unconfigured")
+	assert.EqualError(t, err, "no bootstrap servers for context unconfigured (set UNCONFIGURED_KAFKA_BOOTSTRAPSERVERS)")
+
+	// Names which can't be an env-var prefix are rejected:
+	_, err = KafkaContext("prod-eu")
+	assert.ErrorContains(t, err, "invalid context name prod-eu")
+
+	// Bad values are reported:
+	t.Setenv("STAGING_KAFKA_REQUIREDACKS", "all")
+	_, err = KafkaContext("staging")
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DialFunc opens network connections to brokers:
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// addressMap rewrites broker addresses (either "host:port" or just "host"):
+type addressMap map[string]string
+
+// parseAddressMap parses a list of "from=to" address rewrites:
+func parseAddressMap(entries []string) (addressMap, error) {
+	rewrites := make(addressMap)
+
+	for _, entry := range entries {
+		from, to, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid address map entry %q (expected from=to)", entry)
+		}
+		rewrites[from] = to
+	}
+
+	return rewrites, nil
+}
+
+// rewrite returns the address we should actually connect to (exact "host:port" matches take priority over "host" matches):
+func (am addressMap) rewrite(address string) string {
+	if to, ok := am[address]; ok {
+		return to
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+
+	// Host-only rewrites keep the original port unless they specify their own:
+	if to, ok := am[host]; ok {
+		if _, _, err := net.SplitHostPort(to); err == nil {
+			return to
+		}
+		return net.JoinHostPort(to, port)
+	}
+
+	return address
+}
+
+// RewriteAddress applies our address map to a broker address:
+func (kc *KafkaConfig) RewriteAddress(address string) (string, error) {
+	rewrites, err := parseAddressMap(kc.AddressMap)
+	if err != nil {
+		return "", err
+	}
+
+	return rewrites.rewrite(address), nil
+}
+
+// DialFunc returns a function which dials brokers (applying our address map and proxy):
+func (kc *KafkaConfig) DialFunc() (DialFunc, error) {
+	rewrites, err := parseAddressMap(kc.AddressMap)
+	if err != nil {
+		return nil, err
+	}
+
+	netDialer := &net.Dialer{
+		KeepAlive: 30 * time.Second,
+		Timeout:   10 * time.Second,
+	}
+
+	// Tunnel through a proxy (if one is configured):
+	dialer, err := kc.proxyDialer(netDialer)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, rewrites.rewrite(address))
+	}, nil
+}
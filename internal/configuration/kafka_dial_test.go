@@ -0,0 +1,44 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressMap(t *testing.T) {
+
+	// Make a config with some rewrites:
+	kafkaConfig := &KafkaConfig{
+		AddressMap: []string{
+			"b-1.internal:9092=localhost:19092",
+			"b-2.internal=localhost",
+			"b-3.internal=127.0.0.1:39092",
+		},
+	}
+
+	// Exact matches get rewritten:
+	rewritten, err := kafkaConfig.RewriteAddress("b-1.internal:9092")
+	assert.NoError(t, err, "Error while rewriting an address")
+	assert.Equal(t, "localhost:19092", rewritten)
+
+	// Host matches keep their port:
+	rewritten, err = kafkaConfig.RewriteAddress("b-2.internal:9094")
+	assert.NoError(t, err, "Error while rewriting an address")
+	assert.Equal(t, "localhost:9094", rewritten)
+
+	// Unless the rewrite has its own:
+	rewritten, err = kafkaConfig.RewriteAddress("b-3.internal:9092")
+	assert.NoError(t, err, "Error while rewriting an address")
+	assert.Equal(t, "127.0.0.1:39092", rewritten)
+
+	// Everything else is left alone:
+	rewritten, err = kafkaConfig.RewriteAddress("b-1.internal:9094")
+	assert.NoError(t, err, "Error while rewriting an address")
+	assert.Equal(t, "b-1.internal:9094", rewritten)
+
+	// Invalid entries are rejected:
+	kafkaConfig.AddressMap = []string{"b-1.internal:9092"}
+	_, err = kafkaConfig.RewriteAddress("b-1.internal:9092")
+	assert.Error(t, err, "Expected an invalid address map to be rejected")
+}
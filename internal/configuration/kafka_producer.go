@@ -0,0 +1,28 @@
+package configuration
+
+import (
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Producer returns a Kafka Producer based on our config (leave the topic blank to set it per message):
+func (kc *KafkaConfig) Producer(logger *logrus.Logger, topicName string) (*kafka.Writer, error) {
+
+	// Prepare a transport (with our custom auth settings):
+	transport, err := kc.Transport(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Put a writer together with our config:
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(kc.BootstrapServers...),
+		ErrorLogger:  &kafkaErrorLogger{logger: logger},
+		Logger:       &kafkaLogger{logger: logger},
+		RequiredAcks: kafka.RequiredAcks(kc.RequiredAcks),
+		Topic:        topicName,
+		Transport:    transport,
+	}
+
+	return writer, nil
+}
@@ -0,0 +1,133 @@
+package configuration
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	proxySchemeHTTP   = "http"
+	proxySchemeSOCKS5 = "socks5"
+)
+
+// contextDialer opens network connections (honouring the context):
+type contextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// proxyDialer returns a dialer which tunnels connections through our proxy (or the forward dialer if no proxy is configured):
+func (kc *KafkaConfig) proxyDialer(forward *net.Dialer) (contextDialer, error) {
+	if kc.ProxyURL == "" {
+		return forward, nil
+	}
+
+	// Parse the proxy URL:
+	proxyURL, err := url.Parse(kc.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q (no host)", kc.ProxyURL)
+	}
+
+	// Credentials can come from the URL, but the explicit config takes priority:
+	username := proxyURL.User.Username()
+	password, _ := proxyURL.User.Password()
+	if kc.ProxyUsername != "" {
+		username = kc.ProxyUsername
+		password = kc.ProxyPassword
+	}
+
+	switch proxyURL.Scheme {
+
+	case proxySchemeSOCKS5, "socks5h":
+
+		// Hostnames are resolved by the proxy:
+		var auth *proxy.Auth
+		if username != "" {
+			auth = &proxy.Auth{
+				User:     username,
+				Password: password,
+			}
+		}
+		socksDialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, forward)
+		if err != nil {
+			return nil, err
+		}
+		return socksDialer.(contextDialer), nil
+
+	case proxySchemeHTTP:
+
+		return &httpConnectDialer{
+			forward:  forward,
+			host:     proxyURL.Host,
+			password: password,
+			username: username,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s", proxyURL.Scheme)
+	}
+}
+
+// httpConnectDialer tunnels connections through an HTTP proxy (using CONNECT):
+type httpConnectDialer struct {
+	forward  *net.Dialer
+	host     string
+	password string
+	username string
+}
+
+// DialContext connects to the proxy and asks it to open a tunnel to the address:
+func (hcd *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+
+	// Connect to the proxy itself:
+	conn, err := hcd.forward.DialContext(ctx, network, hcd.host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to proxy: %w", err)
+	}
+
+	// Don't let the CONNECT exchange outlive the context:
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Ask for a tunnel:
+	connectRequest := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if hcd.username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(hcd.username + ":" + hcd.password))
+		connectRequest.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := connectRequest.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to send CONNECT to proxy: %w", err)
+	}
+
+	// Make sure the proxy agreed (brokers never speak first, so nothing else can be buffered here):
+	response, err := http.ReadResponse(bufio.NewReader(conn), connectRequest)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to read CONNECT response from proxy: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", address, response.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,205 @@
+package configuration
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxySOCKS5(t *testing.T) {
+
+	// Run an echo server for the proxy to connect to:
+	echoAddress := testEchoServer(t)
+
+	// Run a SOCKS5 server which requires auth:
+	requestedAddresses := make(chan string, 1)
+	proxyAddress := testSOCKS5Server(t, "user", "pass", requestedAddresses)
+
+	// Make a config which rewrites an advertised broker to the echo server and dials through the proxy:
+	kafkaConfig := &KafkaConfig{
+		AddressMap: []string{"b-1.internal:9092=" + echoAddress},
+		ProxyURL:   "socks5://user:pass@" + proxyAddress,
+	}
+	dialFunc, err := kafkaConfig.DialFunc()
+	assert.NoError(t, err, "Error while preparing a dial function")
+
+	// Dial the advertised broker:
+	conn, err := dialFunc(context.Background(), "tcp", "b-1.internal:9092")
+	assert.NoError(t, err, "Error while dialing through the proxy")
+	defer conn.Close()
+
+	// Make sure the proxy was asked for the rewritten address:
+	assert.Equal(t, echoAddress, <-requestedAddresses)
+
+	// Make sure the tunnel works:
+	testEcho(t, conn)
+
+	// Make sure bad credentials are refused:
+	kafkaConfig.ProxyPassword = "wrong"
+	kafkaConfig.ProxyUsername = "user"
+	dialFunc, err = kafkaConfig.DialFunc()
+	assert.NoError(t, err, "Error while preparing a dial function")
+	_, err = dialFunc(context.Background(), "tcp", "b-1.internal:9092")
+	assert.Error(t, err, "Expected the proxy to refuse bad credentials")
+}
+
+func TestProxyHTTPConnect(t *testing.T) {
+
+	// Run an echo server for the proxy to connect to:
+	echoAddress := testEchoServer(t)
+
+	// Run an HTTP CONNECT proxy which requires auth:
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err, "Error while starting an HTTP proxy")
+	defer proxyListener.Close()
+	go http.Serve(proxyListener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+
+	// Dial the echo server through the proxy:
+	kafkaConfig := &KafkaConfig{
+		ProxyURL:      "http://" + proxyListener.Addr().String(),
+		ProxyPassword: "pass",
+		ProxyUsername: "user",
+	}
+	dialFunc, err := kafkaConfig.DialFunc()
+	assert.NoError(t, err, "Error while preparing a dial function")
+	conn, err := dialFunc(context.Background(), "tcp", echoAddress)
+	assert.NoError(t, err, "Error while dialing through the proxy")
+	defer conn.Close()
+
+	// Make sure the tunnel works:
+	testEcho(t, conn)
+}
+
+// testEcho makes sure a connection echoes what we send:
+func testEcho(t *testing.T, conn net.Conn) {
+	_, err := conn.Write([]byte("ping\n"))
+	assert.NoError(t, err, "Error while writing to the tunnel")
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.NoError(t, err, "Error while reading from the tunnel")
+	assert.Equal(t, "ping\n", line)
+}
+
+// testEchoServer runs a TCP server which echoes everything back:
+func testEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err, "Error while starting an echo server")
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// testSOCKS5Server runs a minimal SOCKS5 server (username/password auth, CONNECT only):
+func testSOCKS5Server(t *testing.T, username, password string, requestedAddresses chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err, "Error while starting a SOCKS5 server")
+	t.Cleanup(func() { listener.Close() })
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		// Greeting (we only offer username/password auth):
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(reader, make([]byte, header[1])); err != nil {
+			return
+		}
+		conn.Write([]byte{0x05, 0x02})
+
+		// Username/password:
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+		user := make([]byte, header[1])
+		io.ReadFull(reader, user)
+		passwordLength, _ := reader.ReadByte()
+		pass := make([]byte, passwordLength)
+		io.ReadFull(reader, pass)
+		if string(user) != username || string(pass) != password {
+			conn.Write([]byte{0x01, 0x01})
+			return
+		}
+		conn.Write([]byte{0x01, 0x00})
+
+		// CONNECT request:
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return
+		}
+		var host string
+		switch request[3] {
+		case 0x01:
+			ip := make([]byte, 4)
+			io.ReadFull(reader, ip)
+			host = net.IP(ip).String()
+		case 0x03:
+			hostLength, _ := reader.ReadByte()
+			name := make([]byte, hostLength)
+			io.ReadFull(reader, name)
+			host = string(name)
+		default:
+			return
+		}
+		port := make([]byte, 2)
+		io.ReadFull(reader, port)
+		address := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+		requestedAddresses <- address
+
+		// Connect upstream and reply:
+		upstream, err := net.Dial("tcp", address)
+		if err != nil {
+			conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer upstream.Close()
+		conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+		go io.Copy(upstream, reader)
+		io.Copy(conn, upstream)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
@@ -0,0 +1,67 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+)
+
+// SchemaRegistryConfig configures the schema registry client:
+type SchemaRegistryConfig struct {
+	CACert             string        `env:"SCHEMAREGISTRY_CACERT"`                                // Path to a CA certificate (PEM) to trust
+	CertFile           string        `env:"SCHEMAREGISTRY_CERTFILE"`                              // Path to a client certificate (PEM) for mTLS
+	InsecureSkipVerify bool          `env:"SCHEMAREGISTRY_INSECURESKIPVERIFY" envDefault:"false"` // Set this to true to skip TLS verification
+	KeyFile            string        `env:"SCHEMAREGISTRY_KEYFILE"`                               // Path to a client key (PEM) for mTLS
+	Password           string        `env:"SCHEMAREGISTRY_PASSWORD"`                              // Basic-auth password
+	Timeout            time.Duration `env:"SCHEMAREGISTRY_TIMEOUT" envDefault:"10s"`              // Timeout for registry requests
+	URL                string        `env:"SCHEMAREGISTRY_URL"`                                   // eg "https://registry:8081"
+	Username           string        `env:"SCHEMAREGISTRY_USERNAME"`                              // Basic-auth username
+}
+
+// Client returns a schema registry client based on our config:
+func (src *SchemaRegistryConfig) Client() (*schemaregistry.Client, error) {
+	if src.URL == "" {
+		return nil, fmt.Errorf("no schema registry URL has been configured")
+	}
+
+	// Prepare a TLS config:
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: src.InsecureSkipVerify,
+	}
+
+	// Trust a custom CA:
+	if src.CACert != "" {
+		caCert, err := os.ReadFile(src.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read schema registry CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in %s", src.CACert)
+		}
+	}
+
+	// Present a client certificate:
+	if src.CertFile != "" || src.KeyFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(src.CertFile, src.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load schema registry client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	// Prepare an HTTP client:
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	httpClient := &http.Client{
+		Timeout:   src.Timeout,
+		Transport: transport,
+	}
+
+	return schemaregistry.New(src.URL, src.Username, src.Password, httpClient), nil
+}
@@ -0,0 +1,168 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Encodings used for binary-safe data:
+const (
+	EncodingBase64 = "base64"
+	EncodingUTF8   = "utf8"
+)
+
+// Timestamp types (named as in the "message.timestamp.type" topic config):
+const (
+	TimestampTypeCreateTime    = "CreateTime"
+	TimestampTypeLogAppendTime = "LogAppendTime"
+)
+
+// Control record types (transaction markers):
+const (
+	ControlAbort  = "ABORT"
+	ControlCommit = "COMMIT"
+)
+
+// Envelope is the canonical JSON representation of a Kafka record (one per line in dumps):
+type Envelope struct {
+	Topic         string            `json:"topic"`
+	Partition     int               `json:"partition"`
+	Offset        int64             `json:"offset"`
+	Timestamp     time.Time         `json:"timestamp"`
+	TimestampType string            `json:"timestamp_type,omitempty"`
+	Key           *Data             `json:"key"`
+	Value         *Data             `json:"value"`
+	Headers       map[string]string `json:"headers"`
+	HeaderList    []Header          `json:"header_list"`
+	Size          int               `json:"size"`
+	Violations    []string          `json:"violations,omitempty"`
+	Transaction   *Transaction      `json:"transaction,omitempty"`
+}
+
+// Transaction describes a record from a transactional producer (only known when reading uncommitted with transaction details):
+type Transaction struct {
+	Aborted    bool   `json:"aborted"`
+	Control    string `json:"control,omitempty"`
+	ProducerID int64  `json:"producer_id"`
+}
+
+// Data holds some (possibly binary) bytes, plus an optional decoded rendering:
+type Data struct {
+	Encoding string          `json:"encoding"`
+	Data     string          `json:"data"`
+	Decoded  json.RawMessage `json:"decoded,omitempty"`
+}
+
+// Header is a single header (these are kept in order, and names can repeat):
+type Header struct {
+	Key   string `json:"key"`
+	Value *Data  `json:"value"`
+}
+
+// New wraps a message in an envelope:
+func New(message kafka.Message) *Envelope {
+	envelope := &Envelope{
+		Topic:      message.Topic,
+		Partition:  message.Partition,
+		Offset:     message.Offset,
+		Timestamp:  message.Time,
+		Key:        NewData(message.Key),
+		Value:      NewData(message.Value),
+		Headers:    make(map[string]string, len(message.Headers)),
+		HeaderList: make([]Header, len(message.Headers)),
+		Size:       len(message.Key) + len(message.Value),
+	}
+
+	for i, header := range message.Headers {
+		envelope.HeaderList[i] = Header{
+			Key:   header.Key,
+			Value: NewData(header.Value),
+		}
+		envelope.Headers[header.Key] = string(header.Value)
+		envelope.Size += len(header.Key) + len(header.Value)
+	}
+
+	return envelope
+}
+
+// NewData encodes some bytes (UTF-8 text is kept readable, anything else becomes base64, and nil stays null):
+func NewData(raw []byte) *Data {
+	if raw == nil {
+		return nil
+	}
+
+	if utf8.Valid(raw) {
+		return &Data{
+			Encoding: EncodingUTF8,
+			Data:     string(raw),
+		}
+	}
+
+	return &Data{
+		Encoding: EncodingBase64,
+		Data:     base64.StdEncoding.EncodeToString(raw),
+	}
+}
+
+// SetDecoded adds a decoded rendering (embedded as JSON if it is JSON, otherwise as a string):
+func (d *Data) SetDecoded(decoded string) {
+	if d == nil {
+		return
+	}
+
+	if json.Valid([]byte(decoded)) {
+		d.Decoded = json.RawMessage(decoded)
+		return
+	}
+
+	d.Decoded, _ = json.Marshal(decoded)
+}
+
+// Bytes returns the original bytes:
+func (d *Data) Bytes() ([]byte, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	switch d.Encoding {
+	case EncodingUTF8, "":
+		return []byte(d.Data), nil
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(d.Data)
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", d.Encoding)
+	}
+}
+
+// Message unwraps the envelope back into a message (headers come from the ordered list):
+func (e *Envelope) Message() (kafka.Message, error) {
+	message := kafka.Message{
+		Topic:     e.Topic,
+		Partition: e.Partition,
+		Offset:    e.Offset,
+		Time:      e.Timestamp,
+	}
+
+	var err error
+	if message.Key, err = e.Key.Bytes(); err != nil {
+		return message, fmt.Errorf("invalid key: %w", err)
+	}
+	if message.Value, err = e.Value.Bytes(); err != nil {
+		return message, fmt.Errorf("invalid value: %w", err)
+	}
+
+	for _, header := range e.HeaderList {
+		value, err := header.Value.Bytes()
+		if err != nil {
+			return message, fmt.Errorf("invalid header %s: %w", header.Key, err)
+		}
+		message.Headers = append(message.Headers, kafka.Header{Key: header.Key, Value: value})
+	}
+
+	return message, nil
+}
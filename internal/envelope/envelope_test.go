@@ -0,0 +1,53 @@
+package envelope
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+
+	// A message with a binary value, a null key and repeated headers:
+	message := kafka.Message{
+		Topic:     "orders",
+		Partition: 3,
+		Offset:    1000,
+		Time:      time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC),
+		Value:     []byte{0x00, 0xff, 0xfe},
+		Headers: []kafka.Header{
+			{Key: "tenant", Value: []byte("x")},
+			{Key: "trace", Value: []byte{0xc3, 0x28}},
+			{Key: "tenant", Value: []byte("y")},
+		},
+	}
+
+	// Wrap it and render it as JSON:
+	wrapped := New(message)
+	wrapped.Value.SetDecoded(`{"id":1}`)
+	rendered, err := json.Marshal(wrapped)
+	assert.NoError(t, err, "Error while rendering an envelope")
+
+	// Binary data is base64, null stays null, and decoded JSON is embedded:
+	var generic map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rendered, &generic), "Error while parsing an envelope")
+	assert.Nil(t, generic["key"])
+	assert.Equal(t, map[string]interface{}{"encoding": "base64", "data": "AP/+", "decoded": map[string]interface{}{"id": float64(1)}}, generic["value"])
+	assert.Equal(t, float64(3+6+1+5+2+6+1), generic["size"])
+
+	// Read it back:
+	var parsed Envelope
+	assert.NoError(t, json.Unmarshal(rendered, &parsed), "Error while parsing an envelope")
+	unwrapped, err := parsed.Message()
+	assert.NoError(t, err, "Error while unwrapping an envelope")
+	assert.Equal(t, message.Topic, unwrapped.Topic)
+	assert.Equal(t, message.Partition, unwrapped.Partition)
+	assert.Equal(t, message.Offset, unwrapped.Offset)
+	assert.True(t, message.Time.Equal(unwrapped.Time))
+	assert.Nil(t, unwrapped.Key)
+	assert.Equal(t, message.Value, unwrapped.Value)
+	assert.Equal(t, message.Headers, unwrapped.Headers)
+}
@@ -0,0 +1,156 @@
+// Package deleterecords implements the DeleteRecords API (which kafka-go doesn't provide), so it can be sent with a kafka.Client's Transport.
+package deleterecords
+
+import (
+	"sort"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Request asks partition leaders to delete every record before an offset (-1 for the high watermark):
+type Request struct {
+	Topics    []RequestTopic `kafka:"min=v0,max=v1"`
+	TimeoutMs int32          `kafka:"min=v0,max=v1"`
+}
+
+type RequestTopic struct {
+	Name       string             `kafka:"min=v0,max=v1"`
+	Partitions []RequestPartition `kafka:"min=v0,max=v1"`
+}
+
+type RequestPartition struct {
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	Offset         int64 `kafka:"min=v0,max=v1"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DeleteRecords }
+
+// Broker routes the request to the leader of its first partition (Split makes sure they all have the same leader):
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	for _, topic := range r.Topics {
+		for _, partition := range topic.Partitions {
+			if broker, ok := cluster.Brokers[leader(cluster, topic.Name, partition.PartitionIndex)]; ok {
+				return broker, nil
+			}
+		}
+	}
+	return protocol.Broker{ID: -1}, nil
+}
+
+// Split makes one request for each partition leader:
+func (r *Request) Split(cluster protocol.Cluster) ([]protocol.Message, protocol.Merger, error) {
+	byLeader := make(map[int32]*Request)
+	var leaders []int32
+
+	for _, topic := range r.Topics {
+		for _, partition := range topic.Partitions {
+			leaderID := leader(cluster, topic.Name, partition.PartitionIndex)
+			request, ok := byLeader[leaderID]
+			if !ok {
+				request = &Request{TimeoutMs: r.TimeoutMs}
+				byLeader[leaderID] = request
+				leaders = append(leaders, leaderID)
+			}
+
+			// Keep the partitions of each topic together:
+			if len(request.Topics) == 0 || request.Topics[len(request.Topics)-1].Name != topic.Name {
+				request.Topics = append(request.Topics, RequestTopic{Name: topic.Name})
+			}
+			last := &request.Topics[len(request.Topics)-1]
+			last.Partitions = append(last.Partitions, partition)
+		}
+	}
+
+	sort.Slice(leaders, func(i, j int) bool { return leaders[i] < leaders[j] })
+	messages := make([]protocol.Message, len(leaders))
+	for i, leaderID := range leaders {
+		messages[i] = byLeader[leaderID]
+	}
+
+	return messages, new(Response), nil
+}
+
+type Response struct {
+	ThrottleTimeMs int32           `kafka:"min=v0,max=v1"`
+	Topics         []ResponseTopic `kafka:"min=v0,max=v1"`
+}
+
+type ResponseTopic struct {
+	Name       string              `kafka:"min=v0,max=v1"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v1"`
+}
+
+type ResponsePartition struct {
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	LowWatermark   int64 `kafka:"min=v0,max=v1"`
+	ErrorCode      int16 `kafka:"min=v0,max=v1"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DeleteRecords }
+
+// Merge combines the responses from each leader (partitions whose leader couldn't be reached get an unknown error):
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (protocol.Message, error) {
+	topics := make(map[string][]ResponsePartition)
+	errors := 0
+
+	for i, result := range results {
+		message, err := protocol.Result(result)
+		if err != nil {
+			for _, topic := range requests[i].(*Request).Topics {
+				for _, partition := range topic.Partitions {
+					topics[topic.Name] = append(topics[topic.Name], ResponsePartition{
+						PartitionIndex: partition.PartitionIndex,
+						LowWatermark:   -1,
+						ErrorCode:      -1,
+					})
+				}
+			}
+			errors++
+			continue
+		}
+
+		response := message.(*Response)
+		if r.ThrottleTimeMs < response.ThrottleTimeMs {
+			r.ThrottleTimeMs = response.ThrottleTimeMs
+		}
+		for _, topic := range response.Topics {
+			topics[topic.Name] = append(topics[topic.Name], topic.Partitions...)
+		}
+	}
+
+	if errors > 0 && errors == len(results) {
+		_, err := protocol.Result(results[0])
+		return nil, err
+	}
+
+	r.Topics = make([]ResponseTopic, 0, len(topics))
+	for name, partitions := range topics {
+		sort.Slice(partitions, func(i, j int) bool {
+			return partitions[i].PartitionIndex < partitions[j].PartitionIndex
+		})
+		r.Topics = append(r.Topics, ResponseTopic{Name: name, Partitions: partitions})
+	}
+	sort.Slice(r.Topics, func(i, j int) bool {
+		return r.Topics[i].Name < r.Topics[j].Name
+	})
+
+	return r, nil
+}
+
+// leader looks up the leader of a partition (or -1 if it isn't known):
+func leader(cluster protocol.Cluster, topicName string, partitionIndex int32) int32 {
+	if partition, ok := cluster.Topics[topicName].Partitions[partitionIndex]; ok {
+		return partition.Leader
+	}
+	return -1
+}
+
+var (
+	_ protocol.BrokerMessage = (*Request)(nil)
+	_ protocol.Splitter      = (*Request)(nil)
+	_ protocol.Merger        = (*Response)(nil)
+)
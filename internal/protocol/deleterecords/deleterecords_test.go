@@ -0,0 +1,90 @@
+package deleterecords
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCluster = protocol.Cluster{
+	Brokers: map[int32]protocol.Broker{
+		1: {ID: 1, Host: "b-1", Port: 9092},
+		2: {ID: 2, Host: "b-2", Port: 9092},
+	},
+	Topics: map[string]protocol.Topic{
+		"orders": {
+			Name: "orders",
+			Partitions: map[int32]protocol.Partition{
+				0: {ID: 0, Leader: 1},
+				1: {ID: 1, Leader: 2},
+				2: {ID: 2, Leader: 1},
+			},
+		},
+	},
+}
+
+func TestSplit(t *testing.T) {
+	request := &Request{
+		TimeoutMs: 30000,
+		Topics: []RequestTopic{
+			{
+				Name: "orders",
+				Partitions: []RequestPartition{
+					{PartitionIndex: 0, Offset: 10},
+					{PartitionIndex: 1, Offset: 20},
+					{PartitionIndex: 2, Offset: -1},
+				},
+			},
+		},
+	}
+
+	// Each leader gets one request (with only its partitions):
+	messages, merger, err := request.Split(testCluster)
+	assert.NoError(t, err, "Error while splitting a request")
+	assert.NotNil(t, merger)
+	assert.Len(t, messages, 2)
+
+	first := messages[0].(*Request)
+	assert.Equal(t, int32(30000), first.TimeoutMs)
+	assert.Equal(t, []RequestPartition{{PartitionIndex: 0, Offset: 10}, {PartitionIndex: 2, Offset: -1}}, first.Topics[0].Partitions)
+	broker, err := first.Broker(testCluster)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), broker.ID)
+
+	second := messages[1].(*Request)
+	broker, err = second.Broker(testCluster)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), broker.ID)
+
+	// Partitions we don't know the leader of go to any broker:
+	broker, err = (&Request{Topics: []RequestTopic{{Name: "unknown", Partitions: []RequestPartition{{PartitionIndex: 0}}}}}).Broker(testCluster)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(-1), broker.ID)
+}
+
+func TestMerge(t *testing.T) {
+	requests := []protocol.Message{
+		&Request{Topics: []RequestTopic{{Name: "orders", Partitions: []RequestPartition{{PartitionIndex: 2}, {PartitionIndex: 0}}}}},
+		&Request{Topics: []RequestTopic{{Name: "orders", Partitions: []RequestPartition{{PartitionIndex: 1}}}}},
+	}
+
+	// Results are combined (and sorted), with an unknown error for partitions whose leader failed:
+	merged, err := new(Response).Merge(requests, []interface{}{
+		&Response{ThrottleTimeMs: 5, Topics: []ResponseTopic{{Name: "orders", Partitions: []ResponsePartition{{PartitionIndex: 2, LowWatermark: 7}, {PartitionIndex: 0, LowWatermark: 10}}}}},
+		errors.New("connection refused"),
+	})
+	assert.NoError(t, err, "Error while merging responses")
+	response := merged.(*Response)
+	assert.Equal(t, int32(5), response.ThrottleTimeMs)
+	assert.Equal(t, []ResponsePartition{
+		{PartitionIndex: 0, LowWatermark: 10},
+		{PartitionIndex: 1, LowWatermark: -1, ErrorCode: -1},
+		{PartitionIndex: 2, LowWatermark: 7},
+	}, response.Topics[0].Partitions)
+
+	// Everything failing is an error:
+	_, err = new(Response).Merge(requests[:1], []interface{}{errors.New("connection refused")})
+	assert.Error(t, err)
+}
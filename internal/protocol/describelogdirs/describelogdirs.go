@@ -0,0 +1,70 @@
+// Package describelogdirs implements the DescribeLogDirs API (which kafka-go doesn't provide), so it can be sent with a kafka.Client's Transport.
+package describelogdirs
+
+import (
+	"fmt"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Request asks one broker to describe its log dirs, and the size of the partitions in them (every partition when Topics is nil):
+type Request struct {
+	Topics []RequestTopic `kafka:"min=v0,max=v1,nullable"`
+
+	// brokerID isn't sent (log dirs can only be described by the broker which has them):
+	brokerID int32
+}
+
+type RequestTopic struct {
+	Topic      string  `kafka:"min=v0,max=v1"`
+	Partitions []int32 `kafka:"min=v0,max=v1"`
+}
+
+// NewRequest prepares a request for a broker:
+func NewRequest(brokerID int32, topics []RequestTopic) *Request {
+	return &Request{Topics: topics, brokerID: brokerID}
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeLogDirs }
+
+// Broker routes the request to the broker it was prepared for:
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	broker, ok := cluster.Brokers[r.brokerID]
+	if !ok {
+		return protocol.Broker{}, fmt.Errorf("broker %d not found in the cluster metadata", r.brokerID)
+	}
+	return broker, nil
+}
+
+type Response struct {
+	ThrottleTimeMs int32            `kafka:"min=v0,max=v1"`
+	Results        []ResponseResult `kafka:"min=v0,max=v1"`
+}
+
+// ResponseResult describes one log dir:
+type ResponseResult struct {
+	ErrorCode int16           `kafka:"min=v0,max=v1"`
+	LogDir    string          `kafka:"min=v0,max=v1"`
+	Topics    []ResponseTopic `kafka:"min=v0,max=v1"`
+}
+
+type ResponseTopic struct {
+	Name       string              `kafka:"min=v0,max=v1"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v1"`
+}
+
+// ResponsePartition describes one replica (IsFutureKey is set on the copy a replica is being moved to between log dirs):
+type ResponsePartition struct {
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	PartitionSize  int64 `kafka:"min=v0,max=v1"`
+	OffsetLag      int64 `kafka:"min=v0,max=v1"`
+	IsFutureKey    bool  `kafka:"min=v0,max=v1"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeLogDirs }
+
+var _ protocol.BrokerMessage = (*Request)(nil)
@@ -0,0 +1,59 @@
+package describelogdirs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCluster = protocol.Cluster{
+	Brokers: map[int32]protocol.Broker{
+		1: {ID: 1, Host: "b-1", Port: 9092},
+		2: {ID: 2, Host: "b-2", Port: 9092},
+	},
+}
+
+func TestBroker(t *testing.T) {
+	// Requests go to the broker they were prepared for:
+	broker, err := NewRequest(2, nil).Broker(testCluster)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), broker.ID)
+
+	// Unknown brokers are an error (rather than any broker answering for them):
+	_, err = NewRequest(3, nil).Broker(testCluster)
+	assert.Error(t, err)
+}
+
+func TestEncoding(t *testing.T) {
+	for _, version := range []int16{0, 1} {
+
+		// Requests (with a null topic list meaning every partition):
+		for _, request := range []*Request{NewRequest(1, []RequestTopic{{Topic: "orders", Partitions: []int32{0, 1}}}), NewRequest(1, nil)} {
+			buffer := &bytes.Buffer{}
+			assert.NoError(t, protocol.WriteRequest(buffer, version, 1, "test", request))
+			_, _, _, decoded, err := protocol.ReadRequest(buffer)
+			assert.NoError(t, err, "Error while decoding a request")
+			assert.Equal(t, request.Topics, decoded.(*Request).Topics)
+		}
+
+		// Responses:
+		response := &Response{
+			ThrottleTimeMs: 5,
+			Results: []ResponseResult{
+				{
+					LogDir: "/var/lib/kafka/data",
+					Topics: []ResponseTopic{
+						{Name: "orders", Partitions: []ResponsePartition{{PartitionIndex: 0, PartitionSize: 1024, OffsetLag: 2}}},
+					},
+				},
+			},
+		}
+		buffer := &bytes.Buffer{}
+		assert.NoError(t, protocol.WriteResponse(buffer, version, 1, response))
+		_, decoded, err := protocol.ReadResponse(buffer, protocol.DescribeLogDirs, version)
+		assert.NoError(t, err, "Error while decoding a response")
+		assert.Equal(t, response, decoded)
+	}
+}
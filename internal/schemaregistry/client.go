@@ -0,0 +1,138 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Schema types (as named by the registry):
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeJSON     = "JSON"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+const (
+	defaultSchemaType    = SchemaTypeAvro // The registry leaves the type out for Avro schemas
+	schemaRegistryAccept = "application/vnd.schemaregistry.v1+json"
+)
+
+// Reference points to another schema (by subject and version) which a schema depends on:
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema is a schema retrieved from the registry:
+type Schema struct {
+	ID         int         `json:"id"`
+	References []Reference `json:"references"`
+	Schema     string      `json:"schema"`
+	SchemaType string      `json:"schemaType"`
+}
+
+// Client retrieves (and caches) schemas from a Confluent-compatible schema registry:
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	password   string
+	schemas    map[int]*Schema
+	subjects   map[string]*Schema
+	mutex      sync.Mutex
+	username   string
+}
+
+// New returns a Client for the registry at the given URL:
+func New(baseURL, username, password string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		password:   password,
+		schemas:    make(map[int]*Schema),
+		subjects:   make(map[string]*Schema),
+		username:   username,
+	}
+}
+
+// SchemaByID retrieves a schema by its ID (these never change, so they are cached forever):
+func (c *Client) SchemaByID(id int) (*Schema, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Check the cache first:
+	if schema, ok := c.schemas[id]; ok {
+		return schema, nil
+	}
+
+	// Retrieve the schema from the registry:
+	schema := &Schema{}
+	if err := c.get(fmt.Sprintf("/schemas/ids/%d", id), schema); err != nil {
+		return nil, fmt.Errorf("unable to retrieve schema %d: %w", id, err)
+	}
+	schema.ID = id
+	if schema.SchemaType == "" {
+		schema.SchemaType = defaultSchemaType
+	}
+
+	c.schemas[id] = schema
+	return schema, nil
+}
+
+// SchemaBySubjectVersion retrieves a specific version of a subject (used to resolve references):
+func (c *Client) SchemaBySubjectVersion(subject string, version int) (*Schema, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Check the cache first:
+	cacheKey := fmt.Sprintf("%s/%d", subject, version)
+	if schema, ok := c.subjects[cacheKey]; ok {
+		return schema, nil
+	}
+
+	// Retrieve the schema from the registry:
+	schema := &Schema{}
+	if err := c.get(fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(subject), version), schema); err != nil {
+		return nil, fmt.Errorf("unable to retrieve version %d of subject %s: %w", version, subject, err)
+	}
+	if schema.SchemaType == "" {
+		schema.SchemaType = defaultSchemaType
+	}
+
+	c.subjects[cacheKey] = schema
+	return schema, nil
+}
+
+// get retrieves a path from the registry and decodes the JSON response:
+func (c *Client) get(path string, target interface{}) error {
+
+	// Prepare a request:
+	request, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", schemaRegistryAccept)
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	// Make the request:
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Make sure it worked:
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("registry returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
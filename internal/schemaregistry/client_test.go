@@ -0,0 +1,46 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaByID(t *testing.T) {
+
+	// Run a fake registry which requires basic-auth and counts requests:
+	var requests int
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/schemas/ids/42" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+			return
+		}
+		w.Write([]byte(`{"schema":"\"string\""}`))
+	}))
+	defer registry.Close()
+
+	// Retrieve a schema (twice):
+	client := New(registry.URL+"/", "user", "pass", registry.Client())
+	for i := 0; i < 2; i++ {
+		schema, err := client.SchemaByID(42)
+		assert.NoError(t, err, "Error while retrieving a schema")
+		assert.Equal(t, 42, schema.ID)
+		assert.Equal(t, `"string"`, schema.Schema)
+		assert.Equal(t, SchemaTypeAvro, schema.SchemaType)
+	}
+
+	// Make sure the cache was used:
+	assert.Equal(t, 1, requests)
+
+	// Make sure errors are reported:
+	_, err := client.SchemaByID(43)
+	assert.ErrorContains(t, err, "Schema not found")
+}
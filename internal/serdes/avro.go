@@ -0,0 +1,86 @@
+package serdes
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"github.com/linkedin/goavro/v2"
+)
+
+// Avro renders Confluent wire-format Avro payloads as JSON:
+type Avro struct {
+	codecs   map[int]*goavro.Codec
+	mutex    sync.Mutex
+	registry *schemaregistry.Client
+}
+
+// NewAvro returns an Avro deserializer which looks schemas up in the given registry:
+func NewAvro(registry *schemaregistry.Client) *Avro {
+	return &Avro{
+		codecs:   make(map[int]*goavro.Codec),
+		registry: registry,
+	}
+}
+
+// Deserialize decodes the payload with the schema it refers to:
+func (a *Avro) Deserialize(data []byte) (string, error) {
+
+	// Split the payload:
+	schemaID, payload, err := parseWireFormat(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Get a codec for this schema:
+	codec, err := a.codec(schemaID)
+	if err != nil {
+		return "", err
+	}
+
+	// Decode the payload:
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return "", fmt.Errorf("unable to decode Avro payload with schema %d: %w", schemaID, err)
+	}
+
+	// Render it as JSON:
+	textual, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return "", fmt.Errorf("unable to render Avro payload as JSON: %w", err)
+	}
+
+	return string(textual), nil
+}
+
+// codec returns a (cached) codec for a schema ID:
+func (a *Avro) codec(schemaID int) (*goavro.Codec, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Check the cache first:
+	if codec, ok := a.codecs[schemaID]; ok {
+		return codec, nil
+	}
+
+	// Retrieve the schema:
+	schema, err := a.registry.SchemaByID(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.SchemaType != schemaregistry.SchemaTypeAvro {
+		return nil, fmt.Errorf("schema %d is %s, not %s", schemaID, schema.SchemaType, schemaregistry.SchemaTypeAvro)
+	}
+	if len(schema.References) > 0 {
+		return nil, fmt.Errorf("schema %d has references, which are not supported for Avro", schemaID)
+	}
+
+	// Build a codec which renders standard JSON (rather than Avro's JSON encoding with its wrapped unions):
+	codec, err := goavro.NewCodecForStandardJSONFull(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Avro schema %d: %w", schemaID, err)
+	}
+
+	a.codecs[schemaID] = codec
+	return codec, nil
+}
@@ -0,0 +1,81 @@
+package serdes
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAvroSchema = `{
+	"type": "record",
+	"name": "Order",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "status", "type": ["null", "string"], "default": null}
+	]
+}`
+
+func TestAvro(t *testing.T) {
+
+	// Run a fake registry:
+	registry := testRegistry(t, map[int]schemaregistry.Schema{
+		7: {Schema: testAvroSchema},
+	}, nil)
+
+	// Encode a record in the wire format:
+	codec, err := goavro.NewCodec(testAvroSchema)
+	assert.NoError(t, err, "Error while parsing the test schema")
+	payload, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"id":     int64(123),
+		"status": goavro.Union("string", "FAILED"),
+	})
+	assert.NoError(t, err, "Error while encoding a test record")
+
+	// Decode it:
+	deserialized, err := NewAvro(registry).Deserialize(testWireFormat(7, payload))
+	assert.NoError(t, err, "Error while deserializing")
+	assert.JSONEq(t, `{"id":123,"status":"FAILED"}`, deserialized)
+
+	// Payloads without the magic byte are rejected:
+	_, err = NewAvro(registry).Deserialize(payload)
+	assert.Error(t, err, "Expected a payload without a wire-format header to be rejected")
+}
+
+// testWireFormat prefixes a payload with the Confluent wire-format header:
+func testWireFormat(schemaID int, payload []byte) []byte {
+	header := []byte{wireFormatMagicByte, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], uint32(schemaID))
+	return append(header, payload...)
+}
+
+// testRegistry runs a fake schema registry, serving schemas by ID and referenced schemas by "<subject>/<version>":
+func testRegistry(t *testing.T, schemas map[int]schemaregistry.Schema, subjects map[string]schemaregistry.Schema) *schemaregistry.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for id, schema := range schemas {
+			if r.URL.Path == fmt.Sprintf("/schemas/ids/%d", id) {
+				json.NewEncoder(w).Encode(schema)
+				return
+			}
+		}
+		for subjectVersion, schema := range subjects {
+			subject, version, _ := strings.Cut(subjectVersion, "/")
+			if r.URL.Path == fmt.Sprintf("/subjects/%s/versions/%s", subject, version) {
+				json.NewEncoder(w).Encode(schema)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	return schemaregistry.New(server.URL, "", "", server.Client())
+}
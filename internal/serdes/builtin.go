@@ -0,0 +1,94 @@
+package serdes
+
+import (
+	"fmt"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+)
+
+// Built-in format names:
+const (
+	FormatAvro        = "avro"
+	FormatBase64      = "base64"
+	FormatCBOR        = "cbor"
+	FormatFloat32     = "float32"
+	FormatFloat64     = "float64"
+	FormatHex         = "hex"
+	FormatInt32       = "int32"
+	FormatInt64       = "int64"
+	FormatJSON        = "json"
+	FormatJSONCompact = "json-compact"
+	FormatJSONSchema  = "json-schema"
+	FormatMsgpack     = "msgpack"
+	FormatProtobuf    = "protobuf"
+	FormatString      = "string"
+	FormatUUID        = "uuid"
+)
+
+func init() {
+
+	// Formats which don't need any options:
+	for format, deserializer := range map[string]Deserializer{
+		FormatBase64:      &Base64{},
+		FormatCBOR:        &CBOR{},
+		FormatFloat32:     &Float32{},
+		FormatFloat64:     &Float64{},
+		FormatHex:         &Hex{},
+		FormatInt32:       &Int32{},
+		FormatInt64:       &Int64{},
+		FormatJSON:        &JSON{Indent: "  "},
+		FormatJSONCompact: &JSON{},
+		FormatMsgpack:     &Msgpack{},
+		FormatString:      &String{},
+		FormatUUID:        &UUID{},
+	} {
+		deserializer := deserializer
+		Register(format, func(*Options) (Deserializer, error) { return deserializer, nil })
+	}
+
+	// Formats which use the schema registry:
+	Register(FormatAvro, func(options *Options) (Deserializer, error) {
+		registry, err := options.schemaRegistry()
+		if err != nil {
+			return nil, err
+		}
+		return NewAvro(registry), nil
+	})
+	Register(FormatJSONSchema, func(options *Options) (Deserializer, error) {
+		registry, err := options.schemaRegistry()
+		if err != nil {
+			return nil, err
+		}
+		return NewJSONSchema(registry), nil
+	})
+
+	// Protobuf can use local descriptors (if a message type was given), or the registry:
+	Register(FormatProtobuf, func(options *Options) (Deserializer, error) {
+		switch {
+
+		case options.ProtoMessage == "":
+			registry, err := options.schemaRegistry()
+			if err != nil {
+				return nil, err
+			}
+			return NewProtobufFromRegistry(registry), nil
+
+		case options.ProtoDescriptorSet != "":
+			return NewProtobufFromDescriptorSet(options.ProtoDescriptorSet, options.ProtoMessage)
+
+		case len(options.ProtoFiles) > 0:
+			return NewProtobufFromProtoFiles(options.ProtoImportPaths, options.ProtoFiles, options.ProtoMessage)
+
+		default:
+			return nil, fmt.Errorf("a protobuf message type needs either a descriptor set or some .proto files")
+		}
+	})
+}
+
+// schemaRegistry gets a registry client (if one is available):
+func (o *Options) schemaRegistry() (*schemaregistry.Client, error) {
+	if o == nil || o.SchemaRegistry == nil {
+		return nil, fmt.Errorf("this format needs a schema registry")
+	}
+	return o.SchemaRegistry()
+}
@@ -0,0 +1,58 @@
+package serdes
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestBuiltinFormats(t *testing.T) {
+
+	// Encode some structured payloads:
+	document := map[string]interface{}{"id": 1, "tags": []string{"a", "b"}}
+	msgpackPayload, err := msgpack.Marshal(document)
+	assert.NoError(t, err, "Error while encoding MessagePack")
+	cborPayload, err := cbor.Marshal(document)
+	assert.NoError(t, err, "Error while encoding CBOR")
+
+	for _, testCase := range []struct {
+		format   string
+		payload  []byte
+		expected string
+	}{
+		{FormatString, []byte("hello"), "hello"},
+		{FormatHex, []byte("hello"), "68656c6c6f"},
+		{FormatBase64, []byte("hello"), "aGVsbG8="},
+		{FormatJSON, []byte(`{"a": [1,2]}`), "{\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+		{FormatJSONCompact, []byte(`{"a": [1, 2]}`), `{"a":[1,2]}`},
+		{FormatInt32, []byte{0xff, 0xff, 0xff, 0xfe}, "-2"},
+		{FormatInt64, []byte{0, 0, 0, 0, 0, 0, 0x01, 0x00}, "256"},
+		{FormatFloat32, []byte{0x3f, 0xc0, 0, 0}, "1.5"},
+		{FormatFloat64, []byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, "3.141592653589793"},
+		{FormatUUID, []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}, "123e4567-e89b-12d3-a456-426614174000"},
+		{FormatMsgpack, msgpackPayload, `{"id":1,"tags":["a","b"]}`},
+		{FormatCBOR, cborPayload, `{"id":1,"tags":["a","b"]}`},
+	} {
+		deserializer, err := New(testCase.format, nil)
+		assert.NoError(t, err, "Error while preparing a %s deserializer", testCase.format)
+		deserialized, err := deserializer.Deserialize(testCase.payload)
+		assert.NoError(t, err, "Error while deserializing %s", testCase.format)
+		assert.Equal(t, testCase.expected, deserialized, "Unexpected %s rendering", testCase.format)
+	}
+
+	// Fixed-width formats reject payloads of the wrong size:
+	deserializer, err := New(FormatInt64, nil)
+	assert.NoError(t, err, "Error while preparing a deserializer")
+	_, err = deserializer.Deserialize([]byte{0x01})
+	assert.Error(t, err, "Expected a short payload to be rejected")
+
+	// Registry formats need a registry:
+	_, err = New(FormatAvro, nil)
+	assert.Error(t, err, "Expected avro to need a registry")
+
+	// Unknown formats are rejected:
+	_, err = New("nope", nil)
+	assert.Error(t, err, "Expected an unknown format to be rejected")
+}
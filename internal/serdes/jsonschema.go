@@ -0,0 +1,189 @@
+package serdes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// jsonSchemaBaseURL is where we pretend registry schemas live (so relative references resolve amongst themselves):
+const jsonSchemaBaseURL = "mem://registry/"
+
+// Violation is a single way in which a payload doesn't match its schema:
+type Violation struct {
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+// String renders the violation as "path: message":
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError is returned (along with the rendered payload) when a payload doesn't match its schema:
+type ValidationError struct {
+	SchemaID   int
+	Violations []Violation
+}
+
+// Error lists the violations:
+func (ve *ValidationError) Error() string {
+	violations := make([]string, len(ve.Violations))
+	for i, violation := range ve.Violations {
+		violations[i] = violation.String()
+	}
+	return fmt.Sprintf("payload does not match schema %d (%s)", ve.SchemaID, strings.Join(violations, "; "))
+}
+
+// JSONSchema strips the Confluent wire-format header from JSON payloads and validates them against their schema:
+type JSONSchema struct {
+	mutex    sync.Mutex
+	registry *schemaregistry.Client
+	schemas  map[int]*jsonschema.Schema
+}
+
+// NewJSONSchema returns a JSONSchema deserializer which looks schemas up in the given registry:
+func NewJSONSchema(registry *schemaregistry.Client) *JSONSchema {
+	return &JSONSchema{
+		registry: registry,
+		schemas:  make(map[int]*jsonschema.Schema),
+	}
+}
+
+// Deserialize returns the JSON payload (and a *ValidationError if it doesn't match its schema):
+func (js *JSONSchema) Deserialize(data []byte) (string, error) {
+
+	// Split the payload:
+	schemaID, payload, err := parseWireFormat(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Get the compiled schema:
+	schema, err := js.schema(schemaID)
+	if err != nil {
+		return "", err
+	}
+
+	// Parse the payload:
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return "", fmt.Errorf("unable to parse JSON payload: %w", err)
+	}
+
+	// Validate it:
+	if err := schema.Validate(document); err != nil {
+		validationErr, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return "", err
+		}
+		return string(payload), &ValidationError{
+			SchemaID:   schemaID,
+			Violations: violations(validationErr, nil),
+		}
+	}
+
+	return string(payload), nil
+}
+
+// schema returns a (cached) compiled schema:
+func (js *JSONSchema) schema(schemaID int) (*jsonschema.Schema, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	// Check the cache first:
+	if schema, ok := js.schemas[schemaID]; ok {
+		return schema, nil
+	}
+
+	// Retrieve the schema:
+	registrySchema, err := js.registry.SchemaByID(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	if registrySchema.SchemaType != schemaregistry.SchemaTypeJSON {
+		return nil, fmt.Errorf("schema %d is %s, not %s", schemaID, registrySchema.SchemaType, schemaregistry.SchemaTypeJSON)
+	}
+
+	// Only ever load schemas from the registry:
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("%s is not referenced by schema %d", url, schemaID)
+	}
+
+	// Add the schema and everything it references:
+	schemaURL := fmt.Sprintf("%sschema-%d.json", jsonSchemaBaseURL, schemaID)
+	if err := compiler.AddResource(schemaURL, strings.NewReader(registrySchema.Schema)); err != nil {
+		return nil, fmt.Errorf("unable to parse JSON schema %d: %w", schemaID, err)
+	}
+	if err := js.addReferences(compiler, registrySchema.References, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+
+	// Compile it:
+	schema, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile JSON schema %d: %w", schemaID, err)
+	}
+
+	js.schemas[schemaID] = schema
+	return schema, nil
+}
+
+// addReferences retrieves referenced schemas (recursively) and adds them to the compiler:
+func (js *JSONSchema) addReferences(compiler *jsonschema.Compiler, references []schemaregistry.Reference, added map[string]bool) error {
+	for _, reference := range references {
+		if added[reference.Name] {
+			continue
+		}
+		added[reference.Name] = true
+
+		referencedSchema, err := js.registry.SchemaBySubjectVersion(reference.Subject, reference.Version)
+		if err != nil {
+			return err
+		}
+
+		// Relative names live alongside the main schema:
+		referenceURL := reference.Name
+		if !strings.Contains(referenceURL, "://") {
+			referenceURL = jsonSchemaBaseURL + strings.TrimPrefix(referenceURL, "/")
+		}
+		if err := compiler.AddResource(referenceURL, strings.NewReader(referencedSchema.Schema)); err != nil {
+			return fmt.Errorf("unable to parse referenced JSON schema %s: %w", reference.Name, err)
+		}
+
+		if err := js.addReferences(compiler, referencedSchema.References, added); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// violations flattens a validation error down to its leaves:
+func violations(validationErr *jsonschema.ValidationError, collected []Violation) []Violation {
+	if len(validationErr.Causes) == 0 {
+		path := validationErr.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		return append(collected, Violation{
+			Message: validationErr.Message,
+			Path:    path,
+		})
+	}
+
+	for _, cause := range validationErr.Causes {
+		collected = violations(cause, collected)
+	}
+
+	return collected
+}
@@ -0,0 +1,48 @@
+package serdes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJSONSchema = `{
+	"type": "object",
+	"properties": {
+		"id": {"type": "integer"},
+		"customer": {"$ref": "customer.json"}
+	},
+	"required": ["id"]
+}`
+
+func TestJSONSchema(t *testing.T) {
+
+	// Run a fake registry (with the customer schema as a reference):
+	registry := testRegistry(t, map[int]schemaregistry.Schema{
+		3: {
+			Schema:     testJSONSchema,
+			SchemaType: schemaregistry.SchemaTypeJSON,
+			References: []schemaregistry.Reference{{Name: "customer.json", Subject: "customer", Version: 1}},
+		},
+	}, map[string]schemaregistry.Schema{
+		"customer/1": {
+			Schema:     `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+			SchemaType: schemaregistry.SchemaTypeJSON,
+		},
+	})
+	deserializer := NewJSONSchema(registry)
+
+	// Valid payloads are returned without the header:
+	deserialized, err := deserializer.Deserialize(testWireFormat(3, []byte(`{"id":1,"customer":{"name":"bob"}}`)))
+	assert.NoError(t, err, "Error while deserializing")
+	assert.Equal(t, `{"id":1,"customer":{"name":"bob"}}`, deserialized)
+
+	// Invalid payloads are still returned, but with the violations:
+	deserialized, err = deserializer.Deserialize(testWireFormat(3, []byte(`{"id":"1","customer":{"name":2}}`)))
+	assert.Equal(t, `{"id":"1","customer":{"name":2}}`, deserialized)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr), "Expected a validation error")
+	assert.ElementsMatch(t, []string{"/id", "/customer/name"}, []string{validationErr.Violations[0].Path, validationErr.Violations[1].Path})
+}
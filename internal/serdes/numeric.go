@@ -0,0 +1,73 @@
+package serdes
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Int32 renders big-endian 32-bit integers:
+type Int32 struct{}
+
+// Deserialize decodes the payload as a signed 32-bit integer:
+func (i *Int32) Deserialize(data []byte) (string, error) {
+	if err := expectLength(data, 4, FormatInt32); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(data))), 10), nil
+}
+
+// Int64 renders big-endian 64-bit integers:
+type Int64 struct{}
+
+// Deserialize decodes the payload as a signed 64-bit integer:
+func (i *Int64) Deserialize(data []byte) (string, error) {
+	if err := expectLength(data, 8, FormatInt64); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(int64(binary.BigEndian.Uint64(data)), 10), nil
+}
+
+// Float32 renders big-endian IEEE-754 32-bit floats:
+type Float32 struct{}
+
+// Deserialize decodes the payload as a 32-bit float:
+func (f *Float32) Deserialize(data []byte) (string, error) {
+	if err := expectLength(data, 4, FormatFloat32); err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(data))), 'g', -1, 32), nil
+}
+
+// Float64 renders big-endian IEEE-754 64-bit floats:
+type Float64 struct{}
+
+// Deserialize decodes the payload as a 64-bit float:
+func (f *Float64) Deserialize(data []byte) (string, error) {
+	if err := expectLength(data, 8, FormatFloat64); err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(data)), 'g', -1, 64), nil
+}
+
+// UUID renders 16-byte (big-endian) UUIDs in their canonical form:
+type UUID struct{}
+
+// Deserialize decodes the payload as a UUID:
+func (u *UUID) Deserialize(data []byte) (string, error) {
+	if err := expectLength(data, 16, FormatUUID); err != nil {
+		return "", err
+	}
+	encoded := hex.EncodeToString(data)
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:32], nil
+}
+
+// expectLength makes sure a fixed-width payload is the right size:
+func expectLength(data []byte, length int, format string) error {
+	if len(data) != length {
+		return fmt.Errorf("payload is %d bytes, but %s needs exactly %d", len(data), format, length)
+	}
+	return nil
+}
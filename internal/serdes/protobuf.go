@@ -0,0 +1,278 @@
+package serdes
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// registrySchemaFile is the name we compile registry schemas under:
+const registrySchemaFile = "schema.proto"
+
+// typeResolver resolves message types and extensions (for Any fields):
+type typeResolver interface {
+	protoregistry.ExtensionTypeResolver
+	protoregistry.MessageTypeResolver
+}
+
+// Protobuf renders protobuf payloads as canonical protojson, using either local descriptors or the schema registry:
+type Protobuf struct {
+	files       map[int]linker.Files
+	messageType protoreflect.MessageType
+	mutex       sync.Mutex
+	registry    *schemaregistry.Client
+	resolver    typeResolver
+}
+
+// NewProtobufFromDescriptorSet returns a Protobuf deserializer for a message type in a FileDescriptorSet (eg from "protoc --descriptor_set_out"):
+func NewProtobufFromDescriptorSet(descriptorSetPath, messageName string) (*Protobuf, error) {
+
+	// Read the descriptor set:
+	descriptorSetBytes, err := os.ReadFile(descriptorSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read descriptor set: %w", err)
+	}
+	descriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(descriptorSetBytes, descriptorSet); err != nil {
+		return nil, fmt.Errorf("unable to parse descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(descriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load descriptor set: %w", err)
+	}
+
+	// Find the message type:
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find message type %s: %w", messageName, err)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", messageName)
+	}
+
+	// Build a resolver (for Any fields) from everything in the set:
+	resolver := &protoregistry.Types{}
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		registerMessageTypes(resolver, file.Messages())
+		return true
+	})
+
+	return &Protobuf{
+		messageType: dynamicpb.NewMessageType(messageDescriptor),
+		resolver:    resolver,
+	}, nil
+}
+
+// NewProtobufFromProtoFiles returns a Protobuf deserializer for a message type in some .proto files (found in the import paths):
+func NewProtobufFromProtoFiles(importPaths, protoFiles []string, messageName string) (*Protobuf, error) {
+
+	// Compile the .proto files:
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), protoFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile .proto files: %w", err)
+	}
+
+	// Find the message type:
+	resolver := files.AsResolver()
+	messageType, err := resolver.FindMessageByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find message type %s: %w", messageName, err)
+	}
+
+	return &Protobuf{
+		messageType: messageType,
+		resolver:    resolver,
+	}, nil
+}
+
+// NewProtobufFromRegistry returns a Protobuf deserializer for Confluent wire-format payloads (with schemas from the registry):
+func NewProtobufFromRegistry(registry *schemaregistry.Client) *Protobuf {
+	return &Protobuf{
+		files:    make(map[int]linker.Files),
+		registry: registry,
+	}
+}
+
+// Deserialize decodes the payload and renders it as protojson:
+func (p *Protobuf) Deserialize(data []byte) (string, error) {
+
+	// Local descriptors decode raw payloads (and wire-format ones, as a field number of 0 can never start a raw payload):
+	if p.messageType != nil {
+		if len(data) > 0 && data[0] == wireFormatMagicByte {
+			_, payload, err := parseWireFormat(data)
+			if err != nil {
+				return "", err
+			}
+			if _, data, err = parseMessageIndexes(payload); err != nil {
+				return "", err
+			}
+		}
+		return p.render(p.messageType, p.resolver, data)
+	}
+
+	// Split the payload:
+	schemaID, payload, err := parseWireFormat(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Compile the schema:
+	files, err := p.compile(schemaID)
+	if err != nil {
+		return "", err
+	}
+
+	// Read the message-indexes (which message in the schema this payload is):
+	indexes, payload, err := parseMessageIndexes(payload)
+	if err != nil {
+		return "", err
+	}
+
+	// Walk the indexes down to the message type:
+	messages := files.FindFileByPath(registrySchemaFile).Messages()
+	var messageDescriptor protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || index >= messages.Len() {
+			return "", fmt.Errorf("message index %v is out of range for schema %d", indexes, schemaID)
+		}
+		messageDescriptor = messages.Get(index)
+		messages = messageDescriptor.Messages()
+	}
+
+	return p.render(dynamicpb.NewMessageType(messageDescriptor), files.AsResolver(), payload)
+}
+
+// render decodes a payload as the given message type and renders it as protojson:
+func (p *Protobuf) render(messageType protoreflect.MessageType, resolver typeResolver, payload []byte) (string, error) {
+	message := messageType.New().Interface()
+
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return "", fmt.Errorf("unable to decode payload as %s: %w", messageType.Descriptor().FullName(), err)
+	}
+
+	rendered, err := protojson.MarshalOptions{Resolver: resolver}.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("unable to render %s as JSON: %w", messageType.Descriptor().FullName(), err)
+	}
+
+	return string(rendered), nil
+}
+
+// compile returns the (cached) compiled files for a registry schema (and its references):
+func (p *Protobuf) compile(schemaID int) (linker.Files, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Check the cache first:
+	if files, ok := p.files[schemaID]; ok {
+		return files, nil
+	}
+
+	// Retrieve the schema:
+	schema, err := p.registry.SchemaByID(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.SchemaType != schemaregistry.SchemaTypeProtobuf {
+		return nil, fmt.Errorf("schema %d is %s, not %s", schemaID, schema.SchemaType, schemaregistry.SchemaTypeProtobuf)
+	}
+
+	// Gather the sources of the schema and everything it references (named by their import paths):
+	sources := map[string]string{registrySchemaFile: schema.Schema}
+	if err := p.gatherReferences(schema.References, sources); err != nil {
+		return nil, err
+	}
+
+	// Compile them:
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), registrySchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile protobuf schema %d: %w", schemaID, err)
+	}
+
+	p.files[schemaID] = files
+	return files, nil
+}
+
+// gatherReferences retrieves referenced schemas (recursively):
+func (p *Protobuf) gatherReferences(references []schemaregistry.Reference, sources map[string]string) error {
+	for _, reference := range references {
+		if _, ok := sources[reference.Name]; ok {
+			continue
+		}
+
+		referencedSchema, err := p.registry.SchemaBySubjectVersion(reference.Subject, reference.Version)
+		if err != nil {
+			return err
+		}
+		sources[reference.Name] = referencedSchema.Schema
+
+		if err := p.gatherReferences(referencedSchema.References, sources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseMessageIndexes reads the (zig-zag varint) message-indexes which follow the schema ID in protobuf payloads:
+func parseMessageIndexes(data []byte) ([]int, []byte, error) {
+
+	// The first varint is the number of indexes:
+	count, bytesRead := binary.Varint(data)
+	if bytesRead <= 0 {
+		return nil, nil, fmt.Errorf("unable to read the message-index count")
+	}
+	data = data[bytesRead:]
+
+	// An empty list is shorthand for the first message:
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	if count < 0 || int(count) > len(data) {
+		return nil, nil, fmt.Errorf("invalid message-index count (%d)", count)
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, bytesRead := binary.Varint(data)
+		if bytesRead <= 0 {
+			return nil, nil, fmt.Errorf("unable to read message-index %d", i)
+		}
+		indexes[i] = int(index)
+		data = data[bytesRead:]
+	}
+
+	return indexes, data, nil
+}
+
+// registerMessageTypes adds message types (and their nested types) to a resolver:
+func registerMessageTypes(resolver *protoregistry.Types, messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		resolver.RegisterMessage(dynamicpb.NewMessageType(messages.Get(i)))
+		registerMessageTypes(resolver, messages.Get(i).Messages())
+	}
+}
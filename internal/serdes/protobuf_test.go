@@ -0,0 +1,82 @@
+package serdes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testProtoSchema = `syntax = "proto3";
+package orders;
+
+message Customer {
+  string name = 1;
+}
+
+message Order {
+  int64 id = 1;
+  string status = 2;
+  Customer customer = 3;
+}
+`
+
+func TestProtobuf(t *testing.T) {
+
+	// Write the schema to a temporary import path:
+	importPath := t.TempDir()
+	err := os.WriteFile(filepath.Join(importPath, "orders.proto"), []byte(testProtoSchema), 0600)
+	assert.NoError(t, err, "Error while writing the test schema")
+
+	// Encode an order:
+	payload := testProtoOrder(t, importPath)
+
+	// Decode it with local .proto files:
+	deserializer, err := NewProtobufFromProtoFiles([]string{importPath}, []string{"orders.proto"}, "orders.Order")
+	assert.NoError(t, err, "Error while preparing a deserializer")
+	deserialized, err := deserializer.Deserialize(payload)
+	assert.NoError(t, err, "Error while deserializing")
+	assert.JSONEq(t, `{"id":"123","status":"FAILED","customer":{"name":"bob"}}`, deserialized)
+
+	// Decode it from the registry (Order is the 2nd message in the schema, so it has an index of 1):
+	registry := testRegistry(t, map[int]schemaregistry.Schema{
+		9: {Schema: testProtoSchema, SchemaType: schemaregistry.SchemaTypeProtobuf},
+	}, nil)
+	wirePayload := testWireFormat(9, append([]byte{0x02, 0x02}, payload...))
+	deserialized, err = NewProtobufFromRegistry(registry).Deserialize(wirePayload)
+	assert.NoError(t, err, "Error while deserializing")
+	assert.JSONEq(t, `{"id":"123","status":"FAILED","customer":{"name":"bob"}}`, deserialized)
+
+	// Local descriptors can decode wire-format payloads too:
+	deserialized, err = deserializer.Deserialize(wirePayload)
+	assert.NoError(t, err, "Error while deserializing")
+	assert.JSONEq(t, `{"id":"123","status":"FAILED","customer":{"name":"bob"}}`, deserialized)
+}
+
+// testProtoOrder encodes an order:
+func testProtoOrder(t *testing.T, importPath string) []byte {
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{ImportPaths: []string{importPath}},
+	}
+	files, err := compiler.Compile(context.Background(), "orders.proto")
+	assert.NoError(t, err, "Error while compiling the test schema")
+
+	messages := files[0].Messages()
+	customer := dynamicpb.NewMessage(messages.ByName("Customer"))
+	customer.Set(customer.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("bob"))
+	order := dynamicpb.NewMessage(messages.ByName("Order"))
+	order.Set(order.Descriptor().Fields().ByName("id"), protoreflect.ValueOfInt64(123))
+	order.Set(order.Descriptor().Fields().ByName("status"), protoreflect.ValueOfString("FAILED"))
+	order.Set(order.Descriptor().Fields().ByName("customer"), protoreflect.ValueOfMessage(customer))
+
+	payload, err := proto.Marshal(order)
+	assert.NoError(t, err, "Error while encoding the test order")
+	return payload
+}
@@ -0,0 +1,65 @@
+package serdes
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/chrusty/kafka-cli/internal/schemaregistry"
+)
+
+// Deserializer renders raw message payloads as text:
+type Deserializer interface {
+	Deserialize(data []byte) (string, error)
+}
+
+// Options configures the deserializers which need more than a format name:
+type Options struct {
+	ProtoDescriptorSet string                                 // FileDescriptorSet to find the protobuf message type in
+	ProtoFiles         []string                               // The .proto files to find the protobuf message type in
+	ProtoImportPaths   []string                               // Import paths for the .proto files
+	ProtoMessage       string                                 // Fully-qualified protobuf message type (if blank then schemas come from the registry)
+	SchemaRegistry     func() (*schemaregistry.Client, error) // Provides a schema registry client (only called by formats which need one)
+}
+
+// Factory prepares a deserializer:
+type Factory func(options *Options) (Deserializer, error)
+
+var (
+	factories      = make(map[string]Factory)
+	factoriesMutex sync.RWMutex
+)
+
+// Register makes a deserializer available by format name:
+func Register(format string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+	factories[format] = factory
+}
+
+// New prepares a deserializer for the named format:
+func New(format string, options *Options) (Deserializer, error) {
+	factoriesMutex.RLock()
+	factory, ok := factories[format]
+	factoriesMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %s (expected one of %v)", format, Formats())
+	}
+
+	return factory(options)
+}
+
+// Formats lists the registered format names:
+func Formats() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	formats := make([]string, 0, len(factories))
+	for format := range factories {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
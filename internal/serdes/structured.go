@@ -0,0 +1,74 @@
+package serdes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Msgpack renders MessagePack payloads as JSON:
+type Msgpack struct{}
+
+// Deserialize decodes the payload and renders it as JSON:
+func (m *Msgpack) Deserialize(data []byte) (string, error) {
+	var decoded interface{}
+	if err := msgpack.Unmarshal(data, &decoded); err != nil {
+		return "", fmt.Errorf("unable to decode MessagePack payload: %w", err)
+	}
+	return renderJSON(decoded)
+}
+
+// CBOR renders CBOR payloads as JSON:
+type CBOR struct{}
+
+// Deserialize decodes the payload and renders it as JSON:
+func (c *CBOR) Deserialize(data []byte) (string, error) {
+	var decoded interface{}
+	if err := cbor.Unmarshal(data, &decoded); err != nil {
+		return "", fmt.Errorf("unable to decode CBOR payload: %w", err)
+	}
+	return renderJSON(decoded)
+}
+
+// renderJSON renders a decoded document as compact JSON:
+func renderJSON(decoded interface{}) (string, error) {
+	rendered, err := json.Marshal(jsonSafe(decoded))
+	if err != nil {
+		return "", fmt.Errorf("unable to render payload as JSON: %w", err)
+	}
+	return string(rendered), nil
+}
+
+// jsonSafe converts the types binary formats can decode to (non-string map keys, raw bytes) into ones JSON can represent:
+func jsonSafe(decoded interface{}) interface{} {
+	switch value := decoded.(type) {
+
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, element := range value {
+			converted[fmt.Sprint(key)] = jsonSafe(element)
+		}
+		return converted
+
+	case map[string]interface{}:
+		for key, element := range value {
+			value[key] = jsonSafe(element)
+		}
+		return value
+
+	case []interface{}:
+		for i, element := range value {
+			value[i] = jsonSafe(element)
+		}
+		return value
+
+	case []byte:
+		return base64.StdEncoding.EncodeToString(value)
+
+	default:
+		return value
+	}
+}
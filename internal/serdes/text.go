@@ -0,0 +1,55 @@
+package serdes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// String renders payloads as-is:
+type String struct{}
+
+// Deserialize returns the payload as a string:
+func (s *String) Deserialize(data []byte) (string, error) {
+	return string(data), nil
+}
+
+// Hex renders payloads as hexadecimal:
+type Hex struct{}
+
+// Deserialize returns the payload as hexadecimal:
+func (h *Hex) Deserialize(data []byte) (string, error) {
+	return hex.EncodeToString(data), nil
+}
+
+// Base64 renders payloads as (standard) base64:
+type Base64 struct{}
+
+// Deserialize returns the payload as base64:
+func (b *Base64) Deserialize(data []byte) (string, error) {
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// JSON re-renders JSON payloads (compact, or pretty if an indent is given):
+type JSON struct {
+	Indent string
+}
+
+// Deserialize validates and re-renders the payload:
+func (j *JSON) Deserialize(data []byte) (string, error) {
+	var rendered bytes.Buffer
+
+	if j.Indent == "" {
+		if err := json.Compact(&rendered, data); err != nil {
+			return "", fmt.Errorf("payload is not valid JSON: %w", err)
+		}
+		return rendered.String(), nil
+	}
+
+	if err := json.Indent(&rendered, data, "", j.Indent); err != nil {
+		return "", fmt.Errorf("payload is not valid JSON: %w", err)
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,21 @@
+package serdes
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// wireFormatMagicByte prefixes every payload written by the Confluent serializers:
+const wireFormatMagicByte = 0x0
+
+// parseWireFormat splits a Confluent wire-format payload into its schema ID and the remaining data:
+func parseWireFormat(data []byte) (int, []byte, error) {
+	if len(data) < 5 {
+		return 0, nil, fmt.Errorf("payload is too short (%d bytes) for the schema registry wire format", len(data))
+	}
+	if data[0] != wireFormatMagicByte {
+		return 0, nil, fmt.Errorf("unknown magic byte (%d) for the schema registry wire format", data[0])
+	}
+
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}